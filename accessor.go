@@ -0,0 +1,172 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"sort"
+	"strings"
+)
+
+// GetAll returns the values of the property at the given path. A path is a
+// property name, or a list of property names separated by dots which descends
+// into nested items, e.g. "offers.price". Values found through several nested
+// items are returned in order.
+func (i *Item) GetAll(path string) ValueList {
+	if i == nil {
+		return nil
+	}
+
+	name, rest := i.splitPath(path)
+	values := i.Properties[name]
+	if rest == "" {
+		return values
+	}
+
+	var result ValueList
+	for _, value := range values {
+		if value.Kind == ItemValue {
+			result = append(result, value.Item.GetAll(rest)...)
+		}
+	}
+	return result
+}
+
+// splitPath splits the given path into the name of a property of the item and
+// the remaining path. Property names containing dots, such as absolute URLs,
+// are matched as a whole.
+func (i *Item) splitPath(path string) (string, string) {
+	if _, ok := i.Properties[path]; ok {
+		return path, ""
+	}
+
+	name, rest := path, ""
+	for dot := strings.LastIndexByte(path, '.'); dot >= 0; dot = strings.LastIndexByte(path[:dot], '.') {
+		name, rest = path[:dot], path[dot+1:]
+		if _, ok := i.Properties[name]; ok {
+			break
+		}
+	}
+	return name, rest
+}
+
+// Get returns the first value of the property at the given path, or nil when
+// the property is absent. See GetAll for the path syntax.
+func (i *Item) Get(path string) *Value {
+	if values := i.GetAll(path); len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
+
+// GetString returns the first string value of the property at the given path,
+// or the empty string when there is none. See GetAll for the path syntax.
+func (i *Item) GetString(path string) string {
+	for _, value := range i.GetAll(path) {
+		if value.Kind != ItemValue {
+			return value.Raw
+		}
+	}
+	return ""
+}
+
+// GetItem returns the first nested item of the property at the given path, or
+// nil when there is none. See GetAll for the path syntax.
+func (i *Item) GetItem(path string) *Item {
+	for _, value := range i.GetAll(path) {
+		if value.Kind == ItemValue {
+			return value.Item
+		}
+	}
+	return nil
+}
+
+// GetItems returns the nested items of the property at the given path. See
+// GetAll for the path syntax.
+func (i *Item) GetItems(path string) []*Item {
+	var items []*Item
+	for _, value := range i.GetAll(path) {
+		if value.Kind == ItemValue {
+			items = append(items, value.Item)
+		}
+	}
+	return items
+}
+
+// HasType reports whether the item has the given item type. As schema.org is
+// served over both http and https, the types of the two schema.org namespaces
+// are the same, e.g. "https://schema.org/Event" matches an item of type
+// "http://schema.org/Event".
+func (i *Item) HasType(itemtype string) bool {
+	if i == nil {
+		return false
+	}
+	for _, t := range i.Types {
+		if sameItemType(t, itemtype) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameItemType reports whether the given item types are the same, the http
+// and https schema.org namespaces being the same.
+func sameItemType(a, b string) bool {
+	if a == b {
+		return true
+	}
+	for _, prefix := range schemaOrgPrefixes {
+		if !strings.HasPrefix(a, prefix) {
+			continue
+		}
+		for _, other := range schemaOrgPrefixes {
+			if b == other+a[len(prefix):] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ItemsOfType returns the items, including nested items, that have the given
+// item type, compared as with HasType. Top-level items are visited in
+// document order and nested items depth-first by property name. Items nested
+// in several other items are returned once.
+func (m *Microdata) ItemsOfType(itemtype string) []*Item {
+	var items []*Item
+	seen := make(map[*Item]bool)
+
+	var visit func(item *Item)
+	visit = func(item *Item) {
+		if seen[item] {
+			return
+		}
+		seen[item] = true
+
+		if item.HasType(itemtype) {
+			items = append(items, item)
+		}
+		for _, name := range item.propertyNames() {
+			for _, value := range item.Properties[name] {
+				if value.Kind == ItemValue {
+					visit(value.Item)
+				}
+			}
+		}
+	}
+
+	for _, item := range m.Items {
+		visit(item)
+	}
+	return items
+}
+
+// propertyNames returns the property names of the item in sorted order.
+func (i *Item) propertyNames() []string {
+	names := make([]string, 0, len(i.Properties))
+	for name := range i.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
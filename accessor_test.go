@@ -0,0 +1,148 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+)
+
+var productSnippet = `
+<div itemscope itemtype="http://schema.org/Product">
+	<span itemprop="name">Executive Anvil</span>
+	<span itemprop="http://example.com/vocab.sku">0446310786</span>
+	<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+		<meta itemprop="priceCurrency" content="USD" />
+		<span itemprop="price">119.99</span>
+	</div>
+	<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+		<meta itemprop="priceCurrency" content="EUR" />
+		<span itemprop="price">109.99</span>
+	</div>
+	<div itemprop="review" itemscope itemtype="http://schema.org/Review">
+		<span itemprop="author" itemscope itemtype="http://schema.org/Person">
+			<span itemprop="name">Ellie</span>
+		</span>
+	</div>
+</div>`
+
+func TestItemGet(t *testing.T) {
+	item := ParseData(productSnippet, t).Items[0]
+
+	var testTable = []struct {
+		path     string
+		expected string
+	}{
+		{"name", "Executive Anvil"},
+		{"offers.price", "119.99"},
+		{"review.author.name", "Ellie"},
+		{"http://example.com/vocab.sku", "0446310786"},
+		{"offers", ""},
+		{"missing.path", ""},
+	}
+
+	for _, test := range testTable {
+		if result := item.GetString(test.path); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+
+	if result := item.Get("missing"); result != nil {
+		t.Errorf("Result should have been nil, but it was \"%v\"", result)
+	}
+}
+
+func TestItemGetAll(t *testing.T) {
+	item := ParseData(productSnippet, t).Items[0]
+
+	values := item.GetAll("offers.priceCurrency")
+	expected := []string{"USD", "EUR"}
+	if len(values) != len(expected) {
+		t.Fatalf("Result should have been \"%d\" values, but it was \"%d\"", len(expected), len(values))
+	}
+	for i, value := range values {
+		if value.Raw != expected[i] {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected[i], value.Raw)
+		}
+	}
+}
+
+func TestItemGetItems(t *testing.T) {
+	item := ParseData(productSnippet, t).Items[0]
+
+	result := len(item.GetItems("offers"))
+	expected := 2
+	if result != expected {
+		t.Errorf("Result should have been \"%d\", but it was \"%d\"", expected, result)
+	}
+
+	if author := item.GetItem("review.author"); !author.HasType("http://schema.org/Person") {
+		t.Errorf("Result should have had type \"%s\", but it had \"%v\"", "http://schema.org/Person", author)
+	}
+	if result := item.GetItem("name"); result != nil {
+		t.Errorf("Result should have been nil, but it was \"%v\"", result)
+	}
+}
+
+func TestMicrodataItemsOfType(t *testing.T) {
+	data := ParseData(productSnippet, t)
+
+	var testTable = []struct {
+		itemtype string
+		expected int
+	}{
+		{"http://schema.org/Product", 1},
+		{"http://schema.org/Offer", 2},
+		{"http://schema.org/Person", 1},
+		{"http://schema.org/Place", 0},
+		{"https://schema.org/Offer", 2},
+		{"https://schema.org/Offe", 0},
+	}
+
+	for _, test := range testTable {
+		if result := len(data.ItemsOfType(test.itemtype)); result != test.expected {
+			t.Errorf("Result should have been \"%d\", but it was \"%d\"", test.expected, result)
+		}
+	}
+}
+
+func TestItemGetTemplate(t *testing.T) {
+	data := ParseData(productSnippet, t)
+
+	tmpl := template.Must(template.New("format").Parse(`{{with index .Items 0}}{{.GetString "offers.price"}}{{end}}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatal(err)
+	}
+
+	result := buf.String()
+	expected := "119.99"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestItemHasType(t *testing.T) {
+	item := NewItem()
+	item.addType("http://schema.org/Event")
+	item.addType("http://example.com/Event")
+
+	var testTable = []struct {
+		itemtype string
+		expected bool
+	}{
+		{"http://schema.org/Event", true},
+		{"https://schema.org/Event", true},
+		{"https://schema.org/EventSeries", false},
+		{"http://example.com/Event", true},
+		{"https://example.com/Event", false},
+	}
+
+	for _, test := range testTable {
+		if result := item.HasType(test.itemtype); result != test.expected {
+			t.Errorf("Result should have been %v for \"%s\", but it was %v", test.expected, test.itemtype, result)
+		}
+	}
+}
@@ -4,12 +4,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"text/template"
+	"time"
 
 	"github.com/damian-szulc/microdata"
 )
@@ -24,6 +27,7 @@ func main() {
 
 	baseURL := flag.String("base-url", "http://example.com", "base url to use for the data in the stdin stream.")
 	contentType := flag.String("content-type", "", "content type of the data in the stdin stream.")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout for fetching the document at the given url.")
 	format := flag.String("format", "{{. |jsonMarshal }}", `alternate format for the output of the
 	microdata, using the syntax of package html/template. The default output is
 	equivalent to -f '{{. |jsonMarshal }}'. The struct being passed to the
@@ -41,7 +45,18 @@ func main() {
 
 		type PropertyMap map[string]ValueList
 		
-		type ValueList []interface{}
+		type ValueList []*Value
+
+		type Value struct {
+			Kind    ValueKind
+			Raw     string
+			Element string
+			Item    *Item
+		}
+
+	Items provide the methods Get, GetAll, GetString, GetItem, GetItems and
+	HasType to look up properties by dotted path, e.g.
+	'{{with index .Items 0}}{{.GetString "offers.price"}}{{end}}'.
 
 	The template function "jsonMarshal" calls json.Marshal
 `)
@@ -69,7 +84,8 @@ func main() {
 			os.Exit(1)
 		}
 	default:
-		data, err = microdata.ParseURL(flag.Args()[0])
+		client := &http.Client{Timeout: *timeout}
+		data, err = microdata.ParseURLContext(context.Background(), flag.Args()[0], microdata.WithHTTPClient(client))
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
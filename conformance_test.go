@@ -0,0 +1,102 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the expected results of the conformance fixtures")
+
+// conformanceResult is the expected result of a conformance fixture: the
+// items, in the JSON format of the microdata specification, and the
+// diagnostics.
+type conformanceResult struct {
+	Items       []*Item  `json:"items"`
+	Diagnostics []string `json:"diagnostics,omitempty"`
+}
+
+// TestConformance parses each HTML fixture under testdata/conformance, and
+// compares the result to the JSON file of the same name. The fixtures under
+// testdata/conformance/strict are parsed in strict mode. Run the test with
+// -update to write the results of new fixtures, then review them.
+func TestConformance(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "conformance", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := filepath.Glob(filepath.Join("testdata", "conformance", "strict", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixtures = append(fixtures, strict...)
+	if len(fixtures) == 0 {
+		t.Fatal("Result should have had conformance fixtures")
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimPrefix(filepath.ToSlash(fixture), "testdata/conformance/")
+		t.Run(strings.TrimSuffix(name, ".html"), func(t *testing.T) {
+			result := parseFixture(fixture, strings.HasPrefix(name, "strict/"), t)
+
+			golden := strings.TrimSuffix(fixture, ".html") + ".json"
+			if *update {
+				if err := ioutil.WriteFile(golden, result, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(result, expected) {
+				t.Errorf("Result should have been\n%s\nbut it was\n%s", expected, result)
+			}
+		})
+	}
+}
+
+// parseFixture parses the given fixture, with http://example.com/ as base URL,
+// and returns the result in the format of the expected results.
+func parseFixture(fixture string, strict bool, t *testing.T) []byte {
+	f, err := os.Open(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	u, _ := url.Parse("http://example.com/")
+	opts := []ParseOption{WithBaseURL(u), WithContentType("text/html; charset=utf-8"), WithDiagnostics(nil)}
+	if strict {
+		opts = append(opts, WithStrictMode())
+	}
+	data, err := Parse(f, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := conformanceResult{Items: data.Items}
+	for _, d := range data.Diagnostics {
+		result.Diagnostics = append(result.Diagnostics, d.String())
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
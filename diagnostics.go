@@ -0,0 +1,136 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// Diagnostic codes.
+const (
+	// DiagItemrefCycle reports an itemref leading back to the element of the
+	// item whose properties are crawled, or an item that contains itself. The
+	// repeated element is skipped.
+	DiagItemrefCycle = "itemref-cycle"
+	// DiagDuplicateItemref reports an element reached more than once while
+	// crawling the properties of an item, such as an element referenced
+	// twice or a descendant of the item referenced through itemref. The
+	// repeated element is skipped.
+	DiagDuplicateItemref = "duplicate-itemref"
+	// DiagInvalidJSONLD reports a JSON-LD script block that is not valid
+	// JSON. The remainder of the block is skipped.
+	DiagInvalidJSONLD = "invalid-jsonld"
+	// DiagItemidWithoutItemtype reports an itemid attribute on an element
+	// without itemtype attribute. The itemid is ignored.
+	DiagItemidWithoutItemtype = "itemid-without-itemtype"
+	// DiagUnresolvedItemref reports an itemref token that does not match the
+	// id of any element. The token is ignored.
+	DiagUnresolvedItemref = "unresolved-itemref"
+	// DiagInvalidURL reports a URL attribute that cannot be parsed. The value
+	// is left empty.
+	DiagInvalidURL = "invalid-url"
+	// DiagEmptyItemprop reports an itemprop attribute without property
+	// names. The property is skipped.
+	DiagEmptyItemprop = "empty-itemprop"
+	// DiagDuplicateID reports an id shared by several elements. The first
+	// element with the id, in tree order, is used for itemref.
+	DiagDuplicateID = "duplicate-id"
+	// DiagLimitExceeded reports an item, a property or a value exceeding one
+	// of the limits set with WithLimits. The excess is skipped or truncated.
+	DiagLimitExceeded = "limit-exceeded"
+
+	// The following codes are only reported in strict mode.
+
+	// DiagRelativeItemtype reports an itemtype token that is not an
+	// absolute URL. The type is dropped.
+	DiagRelativeItemtype = "relative-itemtype"
+	// DiagMixedVocabularies reports item types of different vocabularies on
+	// a single item. The types are kept.
+	DiagMixedVocabularies = "mixed-vocabularies"
+	// DiagInvalidPropertyName reports a property name containing "." or ":"
+	// which is not an absolute URL. The property is skipped.
+	DiagInvalidPropertyName = "invalid-property-name"
+	// DiagIgnoredContent reports a content attribute on an element other than
+	// meta. The text content of the element is used instead.
+	DiagIgnoredContent = "ignored-content"
+)
+
+// Diagnostic describes a problem found in the markup while parsing. Problems
+// are not fatal; the parser recovers from them the way the specification
+// prescribes.
+type Diagnostic struct {
+	Code    string
+	Message string
+	// Element is the tag name of the element the problem was found in.
+	Element string
+	// Line and Column locate the start tag of the element in the source,
+	// starting at 1, with the column counted in characters. They are 0 when
+	// the source is not kept, as with ParseHTMLTree, or when parsing without
+	// WithDiagnostics or WithPositions.
+	Line   int
+	Column int
+	Node   *html.Node
+}
+
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, d.Code, d.Message)
+	}
+	return d.Code + ": " + d.Message
+}
+
+// addDiagnostic adds the diagnostic to the diagnostics list.
+func (m *Microdata) addDiagnostic(d Diagnostic) {
+	if d.Node != nil && d.Element == "" {
+		d.Element = d.Node.Data
+	}
+	m.Diagnostics = append(m.Diagnostics, d)
+}
+
+// settleDiagnostics locates the diagnostics of the given results with the
+// given positions of the elements, when given, and reports them to the
+// handler of the configuration.
+func (cfg *parseConfig) settleDiagnostics(positions map[*html.Node]Position, results ...*Microdata) {
+	if positions != nil {
+		locateDiagnostics(positions, results...)
+	}
+	reportDiagnostics(cfg.diagnostics, results...)
+}
+
+// reportDiagnostics calls the given handler, if any, with the diagnostics of
+// the given results.
+func reportDiagnostics(report func(Diagnostic), results ...*Microdata) {
+	if report == nil {
+		return
+	}
+	for _, data := range results {
+		if data == nil {
+			continue
+		}
+		for _, d := range data.Diagnostics {
+			report(d)
+		}
+	}
+}
+
+// locateDiagnostics sets the line and column of the diagnostics of the given
+// results with the given positions of the elements.
+func locateDiagnostics(positions map[*html.Node]Position, results ...*Microdata) {
+	for _, data := range results {
+		if data == nil {
+			continue
+		}
+		for i := range data.Diagnostics {
+			d := &data.Diagnostics[i]
+			if d.Node == nil {
+				continue
+			}
+			if pos, ok := positions[d.Node]; ok {
+				d.Line, d.Column = pos.Line, pos.Column
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// Syntax is a set of structured data syntaxes read by Extract.
+type Syntax uint
+
+// The syntaxes read by Extract.
+const (
+	MicrodataSyntax Syntax = 1 << iota
+	JSONLDSyntax
+	RDFaSyntax
+	OpenGraphSyntax
+	MicroformatsSyntax
+
+	AllSyntaxes = MicrodataSyntax | JSONLDSyntax | RDFaSyntax | OpenGraphSyntax | MicroformatsSyntax
+)
+
+// Document holds the structured data of a HTML document, by syntax. The
+// results of syntaxes that were not read are nil.
+type Document struct {
+	Microdata    *Microdata    `json:"microdata,omitempty"`
+	JSONLD       *Microdata    `json:"jsonld,omitempty"`
+	RDFa         *Microdata    `json:"rdfa,omitempty"`
+	OpenGraph    *Microdata    `json:"opengraph,omitempty"`
+	Microformats *Microformats `json:"microformats,omitempty"`
+
+	// Graph holds the top-level items of all syntaxes, in the order above.
+	// Items sharing an ID, top-level or nested, are joined into a single
+	// item, holding the types and the property values of each. Property names are not translated
+	// between syntaxes, e.g. RDFa property names are IRIs.
+	Graph []*Item `json:"graph"`
+}
+
+// Extract parses the HTML document available in the given reader once and
+// returns its structured data in all the syntaxes enabled with WithSyntaxes.
+// WithBaseURL and WithContentType set the URL used to resolve relative URLs
+// and the content type of the document. WithLimits and WithDiagnostics apply
+// to all syntaxes, and WithPositions to the microdata.
+// Extract stops with the error of ctx when ctx is cancelled between syntaxes.
+func Extract(ctx context.Context, r io.Reader, opts ...ParseOption) (*Document, error) {
+	cfg := newParseConfig(opts)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return extractSource(ctx, r, cfg)
+}
+
+// ExtractTree returns the structured data of the given HTML document. See
+// Extract.
+func ExtractTree(tree *html.Node, opts ...ParseOption) (*Document, error) {
+	return extractTree(context.Background(), tree, nil, newParseConfig(opts))
+}
+
+// ExtractURL fetches the HTML document available at the given URL and returns
+// its structured data. The content type of the response and the URL the
+// document was eventually retrieved from take precedence over WithContentType
+// and WithBaseURL. See Extract and ParseURLContext.
+func ExtractURL(ctx context.Context, urlStr string, opts ...ParseOption) (*Document, error) {
+	cfg := newParseConfig(opts)
+
+	resp, u, err := fetch(ctx, cfg.fetcher, urlStr)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	cfg.baseURL = u
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		cfg.contentType = contentType
+	}
+
+	return extractSource(ctx, resp.Body, cfg)
+}
+
+// extractSource parses the document available in the given reader and
+// returns its structured data, with the diagnostics located in the source
+// when the configuration keeps it.
+func extractSource(ctx context.Context, r io.Reader, cfg *parseConfig) (*Document, error) {
+	tree, positions, err := parseSource(r, cfg.contentType, cfg.keepSource())
+	if err != nil {
+		return nil, err
+	}
+
+	return extractTree(ctx, tree, positions, cfg)
+}
+
+// extractTree returns the structured data of the given node tree in the
+// syntaxes of the given configuration. The positions of the elements in the
+// source the tree was parsed from, when given, are used to locate the
+// diagnostics and record the microdata positions.
+func extractTree(ctx context.Context, tree *html.Node, positions map[*html.Node]Position, cfg *parseConfig) (*Document, error) {
+	doc := &Document{}
+
+	extractors := []struct {
+		syntax Syntax
+		parse  func() error
+	}{
+		{MicrodataSyntax, func() (err error) {
+			p := newTreeParser(tree, cfg.baseURL)
+			p.located = positions
+			p.configure(cfg)
+			doc.Microdata, err = p.parse()
+			return err
+		}},
+		{JSONLDSyntax, func() (err error) {
+			doc.JSONLD, err = parseJSONLDTree(tree, cfg)
+			return err
+		}},
+		{RDFaSyntax, func() (err error) {
+			doc.RDFa, err = parseRDFaTree(tree, cfg)
+			return err
+		}},
+		{OpenGraphSyntax, func() (err error) {
+			doc.OpenGraph, err = parseOpenGraphTree(tree, cfg)
+			return err
+		}},
+		{MicroformatsSyntax, func() (err error) {
+			doc.Microformats, err = parseMicroformatsTree(tree, cfg)
+			return err
+		}},
+	}
+
+	for _, e := range extractors {
+		if cfg.syntaxes&e.syntax == 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := e.parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	others := []*Microdata{doc.JSONLD, doc.RDFa, doc.OpenGraph}
+	if doc.Microformats != nil {
+		// The diagnostics are shared, so they are located in place.
+		others = append(others, &Microdata{Diagnostics: doc.Microformats.Diagnostics})
+	}
+	// The microdata parser reports its own diagnostics.
+	cfg.settleDiagnostics(positions, others...)
+
+	var microformats *Microdata
+	if doc.Microformats != nil {
+		microformats = doc.Microformats.Microdata()
+	}
+	doc.Graph = mergeGraph(doc.Microdata, doc.JSONLD, doc.RDFa, doc.OpenGraph, microformats)
+
+	return doc, nil
+}
+
+// mergeGraph returns the top-level items of the given results, joining the
+// items sharing an ID, top-level or nested, into a single item. The graph is
+// made of copies of the items; the given results are left untouched.
+func mergeGraph(results ...*Microdata) []*Item {
+	// Visit the items reachable from the top-level items, in order.
+	var visited, pending []*Item
+	copies := make(map[*Item]*Item)
+	byID := make(map[string]*Item)
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i] == nil {
+			continue
+		}
+		for j := len(results[i].Items) - 1; j >= 0; j-- {
+			pending = append(pending, results[i].Items[j])
+		}
+	}
+	for len(pending) > 0 {
+		item := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if _, ok := copies[item]; ok || item == nil {
+			continue
+		}
+		visited = append(visited, item)
+
+		if item.ID == "" {
+			copies[item] = &Item{
+				Types:      append([]string{}, item.Types...),
+				Properties: make(PropertyMap, len(item.Properties)),
+				Position:   item.Position,
+			}
+		} else {
+			merged, ok := byID[item.ID]
+			if !ok {
+				merged = NewItem()
+				merged.ID = item.ID
+				byID[item.ID] = merged
+			}
+			for _, t := range item.Types {
+				if !containsString(merged.Types, t) {
+					merged.addType(t)
+				}
+			}
+			copies[item] = merged
+		}
+
+		names := item.propertyNames()
+		for i := len(names) - 1; i >= 0; i-- {
+			values := item.Properties[names[i]]
+			for j := len(values) - 1; j >= 0; j-- {
+				if values[j] != nil && values[j].Kind == ItemValue && values[j].Item != nil {
+					pending = append(pending, values[j].Item)
+				}
+			}
+		}
+	}
+
+	// Copy the values, pointing nested items to their copies.
+	for _, item := range visited {
+		target := copies[item]
+		for name, values := range item.Properties {
+			for _, value := range values {
+				if value != nil && value.Kind == ItemValue && value.Item != nil {
+					nested := *value
+					nested.Item = copies[value.Item]
+					value = &nested
+				}
+				target.addValue(name, value)
+			}
+		}
+	}
+
+	graph := []*Item{}
+	added := make(map[*Item]bool)
+	for _, data := range results {
+		if data == nil {
+			continue
+		}
+		for _, item := range data.Items {
+			if c := copies[item]; c != nil && !added[c] {
+				added[c] = true
+				graph = append(graph, c)
+			}
+		}
+	}
+	return graph
+}
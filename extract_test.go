@@ -0,0 +1,241 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var extractSnippet = `
+<html>
+	<head>
+		<meta property="og:title" content="Penelope's page">
+		<script type="application/ld+json">
+			{"@context": "http://schema.org", "@type": "Person", "@id": "/penelope", "jobTitle": "Writer"}
+		</script>
+	</head>
+	<body>
+		<div itemscope itemtype="http://schema.org/Person" itemid="http://example.com/penelope">
+			<span itemprop="name">Penelope</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/Thing">
+			<span itemprop="name">Anonymous</span>
+		</div>
+		<div class="h-card"><span class="p-name">Penelope</span></div>
+	</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	u, _ := url.Parse("http://example.com/")
+	doc, err := Extract(context.Background(), strings.NewReader(extractSnippet), WithBaseURL(u), WithContentType("text/html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Microdata == nil || doc.JSONLD == nil || doc.RDFa == nil || doc.OpenGraph == nil || doc.Microformats == nil {
+		t.Fatalf("Result should have had all syntaxes, but it was %+v", doc)
+	}
+
+	b, err := json.Marshal(doc.Graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `[` +
+		`{"type":["http://schema.org/Person"],"properties":{"jobTitle":["Writer"],"name":["Penelope"]},"id":"http://example.com/penelope"},` +
+		`{"type":["http://schema.org/Thing"],"properties":{"name":["Anonymous"]}},` +
+		`{"type":["http://ogp.me/ns#"],"properties":{"og:title":["Penelope's page"]}},` +
+		`{"type":["h-card"],"properties":{"name":["Penelope"]}}]`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if n := len(doc.Microdata.Items[0].Properties); n != 1 {
+		t.Errorf("Result should not have changed the microdata item, but it had %d properties", n)
+	}
+}
+
+func TestExtractNestedID(t *testing.T) {
+	html := `
+		<script type="application/ld+json">
+			[{"@context": "http://schema.org", "@type": "Person", "@id": "http://example.com/penelope", "jobTitle": "Writer"},
+			{"@context": "http://schema.org", "@type": "Book", "author": {"@id": "http://example.com/odysseus", "name": "Odysseus"}}]
+		</script>
+		<div itemscope itemtype="http://schema.org/Book">
+			<div itemprop="author" itemscope itemtype="http://schema.org/Person" itemid="http://example.com/penelope">
+				<span itemprop="name">Penelope</span>
+			</div>
+		</div>
+		<div itemscope itemtype="http://schema.org/Person" itemid="http://example.com/odysseus">
+			<span itemprop="jobTitle">King</span>
+		</div>`
+
+	doc, err := Extract(context.Background(), strings.NewReader(html), WithSyntaxes(MicrodataSyntax|JSONLDSyntax))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(doc.Graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	penelope := `{"type":["http://schema.org/Person"],"properties":{"jobTitle":["Writer"],"name":["Penelope"]},"id":"http://example.com/penelope"}`
+	odysseus := `{"type":["http://schema.org/Person"],"properties":{"jobTitle":["King"],"name":["Odysseus"]},"id":"http://example.com/odysseus"}`
+	expected := `[` +
+		`{"type":["http://schema.org/Book"],"properties":{"author":[` + penelope + `]}},` +
+		odysseus + `,` +
+		penelope + `,` +
+		`{"type":["http://schema.org/Book"],"properties":{"author":[` + odysseus + `]}}]`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if doc.Graph[0].GetItem("author") != doc.Graph[2] {
+		t.Error("Result should have joined the nested item with the top-level item")
+	}
+	if n := len(doc.Microdata.Items[0].GetItem("author").Properties); n != 1 {
+		t.Errorf("Result should not have changed the microdata item, but it had %d properties", n)
+	}
+}
+
+func TestExtractSyntaxes(t *testing.T) {
+	doc, err := Extract(context.Background(), strings.NewReader(extractSnippet), WithSyntaxes(JSONLDSyntax|OpenGraphSyntax))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if doc.Microdata != nil || doc.RDFa != nil || doc.Microformats != nil {
+		t.Errorf("Result should only have had JSON-LD and OpenGraph, but it was %+v", doc)
+	}
+	if doc.JSONLD == nil || doc.OpenGraph == nil {
+		t.Fatalf("Result should have had JSON-LD and OpenGraph, but it was %+v", doc)
+	}
+	if len(doc.Graph) != 2 {
+		t.Errorf("Result should have had 2 items in the graph, but it had %d", len(doc.Graph))
+	}
+}
+
+func TestExtractCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Extract(ctx, strings.NewReader(extractSnippet))
+	if err != context.Canceled {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", context.Canceled, err)
+	}
+}
+
+func TestExtractURL(t *testing.T) {
+	fetcher := fetcherFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       &closeRecorder{Reader: strings.NewReader(extractSnippet)},
+			Request:    req,
+		}, nil
+	})
+
+	doc, err := ExtractURL(context.Background(), "http://example.com/", WithHTTPClient(fetcher))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id := doc.JSONLD.Items[0].ID; id != "http://example.com/penelope" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "http://example.com/penelope", id)
+	}
+}
+
+func TestExtractDeepNesting(t *testing.T) {
+	const depth = 1000
+	html := `<div vocab="http://schema.org/" typeof="Thing">` +
+		strings.Repeat(`<div property="child" typeof="Thing">`, depth) + strings.Repeat("</div>", depth) + `</div>` +
+		`<div class="h-card">` + strings.Repeat(`<div class="p-child h-card">`, depth) + "leaf" + strings.Repeat("</div>", depth) + `</div>`
+
+	extract := func(l Limits) *Document {
+		doc, err := Extract(context.Background(), strings.NewReader(html), WithLimits(l),
+			WithSyntaxes(RDFaSyntax|MicroformatsSyntax))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	nesting := func(doc *Document) (int, int) {
+		rdfa := 0
+		for item := doc.RDFa.Items[0]; item != nil; item = item.GetItem("http://schema.org/child") {
+			rdfa++
+		}
+		mf := 0
+		for item := doc.Microformats.Items[0]; item != nil; {
+			mf++
+			values := item.Properties["child"]
+			if len(values) == 0 {
+				break
+			}
+			item = values[0].(*MicroformatItem)
+		}
+		return rdfa, mf
+	}
+
+	if rdfa, mf := nesting(extract(Limits{})); rdfa != depth+1 || mf != depth+1 {
+		t.Errorf("Result should have had %d nested items, but it had %d RDFa and %d microformats", depth+1, rdfa, mf)
+	}
+
+	doc := extract(Limits{MaxDepth: 10})
+	if rdfa, mf := nesting(doc); rdfa != 10 || mf != 10 {
+		t.Errorf("Result should have had %d nested items, but it had %d RDFa and %d microformats", 10, rdfa, mf)
+	}
+	if len(doc.RDFa.Diagnostics) != 1 || doc.RDFa.Diagnostics[0].Code != DiagLimitExceeded ||
+		len(doc.Microformats.Diagnostics) != 1 || doc.Microformats.Diagnostics[0].Code != DiagLimitExceeded {
+		t.Errorf("Result should have had a limit diagnostic per syntax, but it had %v and %v", doc.RDFa.Diagnostics, doc.Microformats.Diagnostics)
+	}
+}
+
+func TestExtractLimits(t *testing.T) {
+	html := `
+		<div vocab="http://schema.org/" typeof="Thing">
+			<span property="name">Long name</span><span property="alternateName">A</span><span property="alternateName">B</span>
+		</div>
+		<div typeof="Thing"></div>
+		<div class="h-card">
+			<span class="p-name">Long name</span><span class="p-nickname">A</span><span class="p-nickname">B</span>
+		</div>
+		<div class="h-card"></div>`
+
+	var reported []Diagnostic
+	doc, err := Extract(context.Background(), strings.NewReader(html),
+		WithSyntaxes(RDFaSyntax|MicroformatsSyntax),
+		WithLimits(Limits{MaxItems: 1, MaxProperties: 2, MaxValueLength: 4}),
+		WithDiagnostics(func(d Diagnostic) { reported = append(reported, d) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.RDFa.Items) != 1 || len(doc.Microformats.Items) != 1 {
+		t.Fatalf("Result should have had 1 item per syntax, but it had %d RDFa and %d microformats", len(doc.RDFa.Items), len(doc.Microformats.Items))
+	}
+	rdfa, mf := doc.RDFa.Items[0], doc.Microformats.Items[0]
+	if name := rdfa.GetString("http://schema.org/name"); name != "Long" || len(rdfa.GetAll("http://schema.org/alternateName")) != 1 {
+		t.Errorf("Result should have had a truncated name and 1 alternate name, but it had %v", rdfa.Properties)
+	}
+	if name := mf.Properties["name"]; len(name) != 1 || name[0] != "Long" || len(mf.Properties["nickname"]) != 1 {
+		t.Errorf("Result should have had a truncated name and 1 nickname, but it had %v", mf.Properties)
+	}
+
+	// A truncated value, a skipped property and a skipped item per syntax.
+	if len(reported) != 6 {
+		t.Errorf("Result should have had 6 reported diagnostics, but it had %v", reported)
+	}
+	for _, d := range reported {
+		if d.Code != DiagLimitExceeded || d.Line == 0 {
+			t.Errorf("Result should have been a located limit diagnostic, but it was %v", d)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// now returns the current time. It is replaced in tests.
+var now = time.Now
+
+// icalZonePattern matches the time zone designator of a date and time.
+var icalZonePattern = regexp.MustCompile(`(?:Z|[+-]\d{2}:?\d{2})$`)
+
+// icalDateTime returns the DTSTART, DTEND or DTSTAMP style property with the
+// given name for the given date, or date and time. Dates become DATE values,
+// dates and times with a time zone UTC times, and others floating times. It
+// reports false when the value cannot be parsed.
+func icalDateTime(name, s string) (string, string, bool) {
+	s = strings.TrimSpace(s)
+	t, err := (&Value{Kind: DateTimeValue, Raw: s}).AsTime()
+	if err != nil {
+		return "", "", false
+	}
+
+	switch {
+	case isoDatePattern.MatchString(s):
+		return name + ";VALUE=DATE", t.Format("20060102"), true
+	case icalZonePattern.MatchString(s):
+		return name, t.UTC().Format("20060102T150405Z"), true
+	}
+	return name, t.Format("20060102T150405"), true
+}
+
+// icalStatus maps the schema.org event statuses to iCalendar statuses.
+var icalStatus = map[string]string{
+	"EventCancelled":   "CANCELLED",
+	"EventPostponed":   "TENTATIVE",
+	"EventRescheduled": "CONFIRMED",
+	"EventScheduled":   "CONFIRMED",
+}
+
+// ToICalendar returns the RFC 5545 iCalendar object holding the events of the
+// given items, which must be vEvent items, with the VEventType of the
+// vocabulary of the HTML microdata specification, or schema.org Event items,
+// including subtypes such as MusicEvent. Events without ID or uid property
+// get a UID derived from their content.
+func ToICalendar(items []*Item) (string, error) {
+	c := &contentLines{}
+	c.add("BEGIN", "VCALENDAR")
+	c.add("VERSION", "2.0")
+	c.add("PRODID", "-//microdata//NONSGML Go microdata//EN")
+
+	stamp := now().UTC().Format("20060102T150405Z")
+	for _, item := range items {
+		var err error
+		switch {
+		case item.HasType(VEventType):
+			err = writeVEvent(c, item, stamp)
+		case isSchemaOrgEvent(item):
+			err = writeSchemaOrgEvent(c, item, stamp)
+		default:
+			var types []string
+			if item != nil {
+				types = item.Types
+			}
+			err = fmt.Errorf("microdata: cannot convert item of type %q to iCalendar", strings.Join(types, " "))
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	c.add("END", "VCALENDAR")
+	return c.String(), nil
+}
+
+// isSchemaOrgEvent reports whether the item has the schema.org Event type, or
+// one of its subtypes, which are all named "...Event".
+func isSchemaOrgEvent(item *Item) bool {
+	if item == nil {
+		return false
+	}
+	for _, t := range item.Types {
+		for _, prefix := range schemaOrgPrefixes {
+			if strings.HasPrefix(t, prefix) && strings.HasSuffix(t, "Event") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// event holds the properties of an event read from a vEvent or a schema.org
+// Event item.
+type event struct {
+	uid, summary, description, location, url, duration, status string
+	start, end                                                 string
+	categories                                                 []string
+}
+
+// write writes the VEVENT component of the event.
+func (e *event) write(c *contentLines, stamp string) error {
+	if e.start == "" {
+		return fmt.Errorf("microdata: cannot convert event %q without start to iCalendar", e.summary)
+	}
+	start, startValue, ok := icalDateTime("DTSTART", e.start)
+	if !ok {
+		return fmt.Errorf("microdata: cannot parse start %q of event %q", e.start, e.summary)
+	}
+
+	uid := e.uid
+	if uid == "" {
+		sum := sha1.Sum([]byte(e.summary + "\x00" + e.start + "\x00" + e.location))
+		uid = hex.EncodeToString(sum[:]) + "@microdata"
+	}
+
+	c.add("BEGIN", "VEVENT")
+	c.add("UID", escapeText(uid))
+	c.add("DTSTAMP", stamp)
+	c.add(start, startValue)
+	if name, value, ok := icalDateTime("DTEND", e.end); ok {
+		c.add(name, value)
+	} else if e.duration != "" {
+		c.add("DURATION", escapeText(e.duration))
+	}
+	if e.summary != "" {
+		c.add("SUMMARY", escapeText(e.summary))
+	}
+	if e.description != "" {
+		c.add("DESCRIPTION", escapeText(e.description))
+	}
+	if e.location != "" {
+		c.add("LOCATION", escapeText(e.location))
+	}
+	if e.url != "" {
+		c.add("URL", e.url)
+	}
+	if e.status != "" {
+		c.add("STATUS", escapeText(e.status))
+	}
+	if len(e.categories) > 0 {
+		categories := make([]string, len(e.categories))
+		for i, category := range e.categories {
+			categories[i] = escapeText(category)
+		}
+		c.add("CATEGORIES", strings.Join(categories, ","))
+	}
+	c.add("END", "VEVENT")
+	return nil
+}
+
+// writeVEvent writes the VEVENT component of a vEvent item.
+func writeVEvent(c *contentLines, item *Item, stamp string) error {
+	e := &event{
+		uid:         item.GetString("uid"),
+		summary:     item.GetString("summary"),
+		description: item.GetString("description"),
+		location:    item.GetString("location"),
+		url:         item.GetString("url"),
+		duration:    item.GetString("duration"),
+		status:      strings.ToUpper(item.GetString("status")),
+		start:       item.GetString("dtstart"),
+		end:         item.GetString("dtend"),
+		categories:  stringValues(item, "category"),
+	}
+	if e.uid == "" {
+		e.uid = item.ID
+	}
+	return e.write(c, stamp)
+}
+
+// writeSchemaOrgEvent writes the VEVENT component of a schema.org Event item.
+func writeSchemaOrgEvent(c *contentLines, item *Item, stamp string) error {
+	e := &event{
+		uid:         item.ID,
+		summary:     item.GetString("name"),
+		description: item.GetString("description"),
+		url:         item.GetString("url"),
+		duration:    item.GetString("duration"),
+		start:       item.GetString("startDate"),
+		end:         item.GetString("endDate"),
+	}
+
+	if value := item.Get("location"); value != nil && value.Kind != ItemValue {
+		e.location = value.Raw
+	} else if value != nil && value.Item != nil {
+		// A Place is written as its name and address.
+		place := value.Item
+		parts := stringValues(place, "name")
+		if address := place.GetItem("address"); address != nil {
+			for _, name := range []string{"streetAddress", "addressLocality", "postalCode", "addressCountry"} {
+				parts = append(parts, stringValues(address, name)...)
+			}
+		} else {
+			parts = append(parts, stringValues(place, "address")...)
+		}
+		e.location = strings.Join(parts, ", ")
+	}
+
+	if status := item.GetString("eventStatus"); status != "" {
+		e.status = icalStatus[status[strings.LastIndexAny(status, "/#")+1:]]
+	}
+	return e.write(c, stamp)
+}
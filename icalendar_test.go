@@ -0,0 +1,124 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToICalendar(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2015, 7, 1, 12, 0, 0, 0, time.UTC) }
+
+	data, err := ParseHTML(strings.NewReader(`
+		<div itemscope itemtype="http://microformats.org/profile/hcalendar#vevent">
+			<span itemprop="summary">Bluesday Tuesday: Money Road</span>
+			<time itemprop="dtstart" datetime="2009-05-05T19:00:00Z">May 5th @ 7pm</time>
+			<time itemprop="dtend" datetime="2009-05-05T21:00:00Z">9pm</time>
+			<a itemprop="url" href="http://example.com/bluesday">More</a>
+			<span itemprop="category">music</span>
+			<span itemprop="category">blues, jazz</span>
+		</div>
+		<div itemscope itemtype="https://schema.org/MusicEvent" itemid="http://example.com/concert">
+			<span itemprop="name">Concert in the park</span>
+			<meta itemprop="startDate" content="2015-07-19">
+			<meta itemprop="duration" content="PT3H">
+			<link itemprop="eventStatus" href="https://schema.org/EventCancelled">
+			<div itemprop="location" itemscope itemtype="https://schema.org/Place">
+				<span itemprop="name">Park</span>
+				<span itemprop="address">Main Street 1, Springfield</span>
+			</div>
+		</div>`), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ToICalendar(data.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//microdata//NONSGML Go microdata//EN\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:4aba07a932e0ab732c00ae3cfccb4565bb9a8226@microdata\r\n" +
+		"DTSTAMP:20150701T120000Z\r\n" +
+		"DTSTART:20090505T190000Z\r\n" +
+		"DTEND:20090505T210000Z\r\n" +
+		"SUMMARY:Bluesday Tuesday: Money Road\r\n" +
+		"URL:http://example.com/bluesday\r\n" +
+		"CATEGORIES:music,blues\\, jazz\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:http://example.com/concert\r\n" +
+		"DTSTAMP:20150701T120000Z\r\n" +
+		"DTSTART;VALUE=DATE:20150719\r\n" +
+		"DURATION:PT3H\r\n" +
+		"SUMMARY:Concert in the park\r\n" +
+		"LOCATION:Park\\, Main Street 1\\, Springfield\r\n" +
+		"STATUS:CANCELLED\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestToICalendarErrors(t *testing.T) {
+	person := NewItem()
+	person.addType("http://schema.org/Person")
+
+	event := NewItem()
+	event.addType("http://schema.org/Event")
+	event.addValue("name", &Value{Kind: TextValue, Raw: "No start"})
+
+	for _, items := range [][]*Item{{person}, {event}, {nil}} {
+		if _, err := ToICalendar(items); err == nil {
+			t.Errorf("Result should have been an error for %v", items)
+		}
+	}
+}
+
+func TestICalDateTime(t *testing.T) {
+	var testTable = []struct {
+		in, name, value string
+	}{
+		{"2015-07-19", "DTSTART;VALUE=DATE", "20150719"},
+		{"2015-07-19T20:00", "DTSTART", "20150719T200000"},
+		{"2015-07-19T20:00:00+02:00", "DTSTART", "20150719T180000Z"},
+	}
+
+	for _, test := range testTable {
+		name, value, ok := icalDateTime("DTSTART", test.in)
+		if !ok || name != test.name || value != test.value {
+			t.Errorf("Result should have been \"%s:%s\", but it was \"%s:%s\"", test.name, test.value, name, value)
+		}
+	}
+}
+
+func TestToICalendarHostileNewlines(t *testing.T) {
+	data, err := ParseHTML(strings.NewReader(`
+		<div itemscope itemtype="http://microformats.org/profile/hcalendar#vevent">
+			<span itemprop="summary">Party</span>
+			<meta itemprop="dtstart" content="2015-07-19">
+			<meta itemprop="url" content="http://x/&#10;ATTACH:http://evil">
+			<meta itemprop="duration" content="PT1H&#13;&#10;ATTENDEE:mailto:evil@example.com">
+			<meta itemprop="status" content="confirmed
+ORGANIZER:mailto:evil@example.com">
+		</div>`), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ToICalendar(data.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContentLines(result, []string{"ATTACH", "ATTENDEE", "ORGANIZER"}, t)
+	if !strings.Contains(result, "URL:http://x/ATTACH:http://evil\r\n") {
+		t.Errorf("Result should have had the URL without line break, but it was \"%s\"", result)
+	}
+}
@@ -0,0 +1,605 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// jsonldContext holds the parts of a JSON-LD context used to expand types.
+type jsonldContext struct {
+	vocab    string
+	prefixes map[string]string
+}
+
+type jsonldParser struct {
+	tree    *html.Node
+	data    *Microdata
+	baseURL *url.URL
+	// limits caps the work done by the parser.
+	limits Limits
+	// itemCount is the number of items read.
+	itemCount int
+	// script is the script element being read.
+	script *html.Node
+}
+
+// newJSONLDParser returns a JSON-LD parser for the given node tree. A nil
+// baseURL is treated as the empty URL.
+func newJSONLDParser(tree *html.Node, baseURL *url.URL) *jsonldParser {
+	if baseURL == nil {
+		baseURL = &url.URL{}
+	}
+
+	return &jsonldParser{
+		tree:    tree,
+		data:    &Microdata{},
+		baseURL: baseURL,
+	}
+}
+
+// parse returns the items of the JSON-LD script blocks in the parser's node
+// tree.
+func (p *jsonldParser) parse() (*Microdata, error) {
+	p.baseURL = documentBaseURL(p.tree, p.baseURL)
+
+	walkNodes(p.tree, func(n *html.Node) {
+		if n.DataAtom != atom.Script {
+			return
+		}
+		if typ, ok := getAttr("type", n); ok && isJSONLDType(typ) {
+			p.readScript(n)
+		}
+	})
+
+	return p.data, nil
+}
+
+// isJSONLDType reports whether the given script type is the JSON-LD media
+// type.
+func isJSONLDType(typ string) bool {
+	if i := strings.IndexByte(typ, ';'); i >= 0 {
+		typ = typ[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(typ), "application/ld+json")
+}
+
+// readScript decodes the content of the given script element and adds the
+// resulting items.
+func (p *jsonldParser) readScript(node *html.Node) {
+	var buf bytes.Buffer
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			buf.WriteString(c.Data)
+		}
+	}
+
+	dec := json.NewDecoder(&buf)
+	dec.UseNumber()
+
+	for {
+		var doc interface{}
+		if err := dec.Decode(&doc); err == io.EOF {
+			return
+		} else if err != nil {
+			p.data.addDiagnostic(Diagnostic{
+				Code:    DiagInvalidJSONLD,
+				Message: "script contains invalid JSON-LD: " + err.Error(),
+				Node:    node,
+			})
+			return
+		}
+		p.script = node
+		p.readTopLevel(doc, &jsonldContext{})
+	}
+}
+
+// readTopLevel adds the nodes of the given top-level JSON-LD value as items.
+func (p *jsonldParser) readTopLevel(doc interface{}, ctx *jsonldContext) {
+	switch doc := doc.(type) {
+	case []interface{}:
+		for _, d := range doc {
+			p.readTopLevel(d, ctx)
+		}
+	case map[string]interface{}:
+		ctx = ctx.with(doc["@context"])
+		if graph, ok := doc["@graph"]; ok {
+			p.readTopLevel(graph, ctx)
+			return
+		}
+		if item := p.readItem(doc, ctx, 1); item != nil {
+			p.data.addItem(item)
+		}
+	}
+}
+
+// readItem converts the given JSON-LD node object, nested at the given depth,
+// to an item. It returns nil when the item exceeds the depth or item limit.
+func (p *jsonldParser) readItem(obj map[string]interface{}, ctx *jsonldContext, depth int) *Item {
+	if max := p.limits.MaxDepth; max > 0 && depth > max {
+		p.warn(fmt.Sprintf("item nested deeper than %d levels; item skipped", max))
+		return nil
+	}
+	if max := p.limits.MaxItems; max > 0 && p.itemCount >= max {
+		if p.itemCount == max {
+			p.warn(fmt.Sprintf("document has more than %d items; remaining items skipped", max))
+			p.itemCount++
+		}
+		return nil
+	}
+	p.itemCount++
+
+	ctx = ctx.with(obj["@context"])
+	item := NewItem()
+
+	for _, typ := range toSlice(obj["@type"]) {
+		if s, ok := typ.(string); ok && len(s) > 0 {
+			item.addType(ctx.expand(s))
+		}
+	}
+
+	if id, ok := obj["@id"].(string); ok {
+		if u, err := p.baseURL.Parse(ctx.expandPrefix(id)); err == nil {
+			item.ID = u.String()
+		}
+	}
+
+	// The properties are read in a fixed order, so the same ones are kept
+	// when the property limit is reached.
+	properties := make([]string, 0, len(obj))
+	for property := range obj {
+		if !strings.HasPrefix(property, "@") {
+			properties = append(properties, property)
+		}
+	}
+	sort.Strings(properties)
+
+	count := 0
+	full := func() bool {
+		max := p.limits.MaxProperties
+		if max > 0 && count >= max {
+			p.warn(fmt.Sprintf("item has more than %d property values; remaining properties skipped", max))
+			return true
+		}
+		return false
+	}
+	for _, property := range properties {
+		// The values of a skipped property are not read, so that their
+		// nested items do not count.
+		if full() {
+			return item
+		}
+		for _, value := range p.readValues(obj[property], ctx, depth) {
+			if full() {
+				return item
+			}
+			if max := p.limits.MaxValueLength; max > 0 && len(value.Raw) > max {
+				p.warn(fmt.Sprintf("value longer than %d bytes; value truncated", max))
+				value.Raw = truncate(value.Raw, max)
+			}
+			item.addValue(property, value)
+			count++
+		}
+	}
+
+	return item
+}
+
+// warn records a diagnostic for the script element being read.
+func (p *jsonldParser) warn(message string) {
+	p.data.addDiagnostic(Diagnostic{
+		Code:    DiagLimitExceeded,
+		Message: message,
+		Node:    p.script,
+	})
+}
+
+// readValues converts the given JSON-LD value, a property value of an item
+// nested at the given depth, to property values.
+func (p *jsonldParser) readValues(v interface{}, ctx *jsonldContext, depth int) ValueList {
+	var values ValueList
+
+	switch v := v.(type) {
+	case []interface{}:
+		for _, elem := range v {
+			values = append(values, p.readValues(elem, ctx, depth)...)
+		}
+	case map[string]interface{}:
+		if list, ok := v["@list"]; ok {
+			return p.readValues(list, ctx, depth)
+		}
+		if set, ok := v["@set"]; ok {
+			return p.readValues(set, ctx, depth)
+		}
+		if literal, ok := v["@value"]; ok {
+			value := p.readLiteral(literal)
+			if typ, ok := v["@type"].(string); ok && value != nil && isDateTimeType(ctx.expand(typ)) {
+				value.Kind = DateTimeValue
+			}
+			if value != nil {
+				values = append(values, value)
+			}
+			break
+		}
+		if id, ok := v["@id"].(string); ok && len(v) == 1 {
+			if u, err := p.baseURL.Parse(ctx.expandPrefix(id)); err == nil {
+				values = append(values, &Value{Kind: URLValue, Raw: u.String(), Element: "script"})
+			}
+			break
+		}
+		if item := p.readItem(v, ctx, depth+1); item != nil {
+			values = append(values, &Value{Kind: ItemValue, Item: item, Element: "script"})
+		}
+	default:
+		if value := p.readLiteral(v); value != nil {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// jsonldDatePattern matches the start of strings that are dates, or dates
+// and times.
+var jsonldDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}|$)`)
+
+// readLiteral converts the given JSON scalar to a property value. Numbers
+// become number values, absolute http(s) URLs become URL values and strings
+// holding a date, or date and time, become datetime values. It returns nil
+// for null.
+func (p *jsonldParser) readLiteral(v interface{}) *Value {
+	value := &Value{Kind: TextValue, Element: "script"}
+
+	switch v := v.(type) {
+	case string:
+		value.Raw = v
+		s := strings.TrimSpace(v)
+		switch {
+		case strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://"):
+			if u, err := p.baseURL.Parse(s); err == nil {
+				value.Kind = URLValue
+				value.Raw = u.String()
+			}
+		case jsonldDatePattern.MatchString(s):
+			if _, err := value.AsTime(); err == nil {
+				value.Kind = DateTimeValue
+			}
+		}
+	case json.Number:
+		value.Kind = NumberValue
+		value.Raw = v.String()
+	case bool:
+		if v {
+			value.Raw = "true"
+		} else {
+			value.Raw = "false"
+		}
+	default:
+		return nil
+	}
+
+	return value
+}
+
+// isDateTimeType reports whether the given expanded datatype IRI is a date or
+// time type.
+func isDateTimeType(typ string) bool {
+	for _, suffix := range []string{"date", "dateTime", "time", "Date", "DateTime", "Time"} {
+		if strings.HasSuffix(typ, "#"+suffix) || strings.HasSuffix(typ, "/"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// with returns the context resulting from applying the given local context.
+// Remote contexts, given as IRIs, are not fetched; an IRI such as
+// "https://schema.org" is used as vocabulary instead.
+func (ctx *jsonldContext) with(local interface{}) *jsonldContext {
+	if local == nil {
+		return ctx
+	}
+
+	result := &jsonldContext{
+		vocab:    ctx.vocab,
+		prefixes: make(map[string]string, len(ctx.prefixes)),
+	}
+	for k, v := range ctx.prefixes {
+		result.prefixes[k] = v
+	}
+
+	for _, c := range toSlice(local) {
+		switch c := c.(type) {
+		case string:
+			result.vocab = c
+		case map[string]interface{}:
+			for k, v := range c {
+				s, ok := v.(string)
+				if !ok {
+					if def, isMap := v.(map[string]interface{}); isMap {
+						s, ok = def["@id"].(string)
+					}
+				}
+				if !ok {
+					continue
+				}
+				if k == "@vocab" {
+					result.vocab = s
+				} else if !strings.HasPrefix(k, "@") {
+					result.prefixes[k] = s
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// expandPrefix expands a compact IRI using the prefixes of the context.
+func (ctx *jsonldContext) expandPrefix(s string) string {
+	if i := strings.IndexByte(s, ':'); i > 0 {
+		if prefix, ok := ctx.prefixes[s[:i]]; ok && !strings.HasPrefix(s[i+1:], "//") {
+			return prefix + s[i+1:]
+		}
+	}
+	return s
+}
+
+// expand expands a type to an IRI using the prefixes and the vocabulary of
+// the context. Absolute IRIs are returned unchanged.
+func (ctx *jsonldContext) expand(s string) string {
+	if expanded := ctx.expandPrefix(s); expanded != s {
+		return expanded
+	}
+	if iri, ok := ctx.prefixes[s]; ok {
+		return iri
+	}
+	if strings.Contains(s, ":") || ctx.vocab == "" {
+		return s
+	}
+	if strings.HasSuffix(ctx.vocab, "/") || strings.HasSuffix(ctx.vocab, "#") {
+		return ctx.vocab + s
+	}
+	return ctx.vocab + "/" + s
+}
+
+// toSlice returns the given JSON value as a slice, wrapping non-array values.
+func toSlice(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// ParseJSONLDTree returns the items described by the JSON-LD script blocks,
+// <script type="application/ld+json">, of the given HTML document. Node
+// objects become items, with @type as types and @id as id, and @graph arrays
+// and multiple script blocks contribute multiple items. Property names are
+// kept as written. The given url is used to resolve the URLs, unless the
+// document contains a base element. It is equivalent to ParseJSONLDTreeWith
+// with WithBaseURL.
+func ParseJSONLDTree(tree *html.Node, u *url.URL) (*Microdata, error) {
+	return ParseJSONLDTreeWith(tree, WithBaseURL(u))
+}
+
+// ParseJSONLDTreeWith returns the items described by the JSON-LD script
+// blocks of the given HTML document. See ParseJSONLDTree. WithBaseURL sets
+// the URL used to resolve the URLs, WithLimits caps the work done, and the
+// handler of WithDiagnostics is called with each diagnostic, without line and
+// column.
+func ParseJSONLDTreeWith(tree *html.Node, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+	data, err := parseJSONLDTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(nil, data)
+	return data, nil
+}
+
+// ParseJSONLD parses the HTML document available in the given reader and
+// returns the items described by its JSON-LD script blocks. See
+// ParseJSONLDTree and ParseHTML. It is equivalent to ParseJSONLDWith with
+// WithContentType and WithBaseURL.
+func ParseJSONLD(r io.Reader, contentType string, u *url.URL) (*Microdata, error) {
+	return ParseJSONLDWith(r, WithContentType(contentType), WithBaseURL(u))
+}
+
+// ParseJSONLDWith parses the HTML document available in the given reader and
+// returns the items described by its JSON-LD script blocks. WithBaseURL and
+// WithContentType apply as with Parse, WithLimits caps the work done, and
+// WithDiagnostics locates the diagnostics in the source.
+func ParseJSONLDWith(r io.Reader, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+	tree, positions, err := parseSource(r, cfg.contentType, cfg.keepSource())
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseJSONLDTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(positions, data)
+	return data, nil
+}
+
+// parseJSONLDTree returns the JSON-LD items of the given node tree with the
+// given configuration, leaving the diagnostics to the caller.
+func parseJSONLDTree(tree *html.Node, cfg *parseConfig) (*Microdata, error) {
+	p := newJSONLDParser(tree, cfg.baseURL)
+	p.limits = cfg.limits
+	return p.parse()
+}
+
+// JSONLDOptions configures MarshalJSONLD.
+type JSONLDOptions struct {
+	// Context is the value of @context. The default is "https://schema.org".
+	// With a schema.org context, schema.org types and property names are
+	// compacted to terms, e.g. "http://schema.org/Person" to "Person".
+	Context string
+	// CollapseSingle writes properties with a single value as scalar
+	// instead of array.
+	CollapseSingle bool
+	// Indent, when not empty, indents the output with the given string.
+	Indent string
+}
+
+// schemaOrgPrefixes holds the IRI prefixes of schema.org terms.
+var schemaOrgPrefixes = []string{"http://schema.org/", "https://schema.org/"}
+
+// isSchemaOrgContext reports whether the given context is the schema.org
+// vocabulary.
+func isSchemaOrgContext(context string) bool {
+	context = strings.TrimSuffix(context, "/")
+	return context == "http://schema.org" || context == "https://schema.org"
+}
+
+type jsonldEncoder struct {
+	opts    JSONLDOptions
+	compact bool
+	// pending holds the items being encoded, to detect items that contain
+	// themselves.
+	pending map[*Item]bool
+}
+
+// MarshalJSONLD returns the JSON-LD encoding of the items: a single node
+// object for a single item, or a @graph of node objects. Types become @type,
+// IDs @id and properties keep their values in order. Number values are
+// written as JSON numbers where possible, other values as strings. An item
+// that contains itself is written as a reference to its @id, and results in
+// an error when it has no ID.
+func (m *Microdata) MarshalJSONLD(opts JSONLDOptions) ([]byte, error) {
+	if opts.Context == "" {
+		opts.Context = "https://schema.org"
+	}
+	e := &jsonldEncoder{
+		opts:    opts,
+		compact: isSchemaOrgContext(opts.Context),
+		pending: make(map[*Item]bool),
+	}
+
+	var doc map[string]interface{}
+	if len(m.Items) == 1 {
+		obj, err := e.encodeItem(m.Items[0])
+		if err != nil {
+			return nil, err
+		}
+		doc = obj
+	} else {
+		graph := make([]interface{}, 0, len(m.Items))
+		for _, item := range m.Items {
+			obj, err := e.encodeItem(item)
+			if err != nil {
+				return nil, err
+			}
+			graph = append(graph, obj)
+		}
+		doc = map[string]interface{}{"@graph": graph}
+	}
+	doc["@context"] = opts.Context
+
+	if opts.Indent != "" {
+		return json.MarshalIndent(doc, "", opts.Indent)
+	}
+	return json.Marshal(doc)
+}
+
+// encodeItem returns the node object of the given item.
+func (e *jsonldEncoder) encodeItem(item *Item) (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	if item.ID != "" {
+		obj["@id"] = item.ID
+	}
+
+	if e.pending[item] {
+		if item.ID == "" {
+			return nil, errCyclicItem
+		}
+		return obj, nil
+	}
+	e.pending[item] = true
+	defer delete(e.pending, item)
+
+	switch len(item.Types) {
+	case 0:
+	case 1:
+		obj["@type"] = e.term(item.Types[0])
+	default:
+		types := make([]string, len(item.Types))
+		for i, t := range item.Types {
+			types[i] = e.term(t)
+		}
+		obj["@type"] = types
+	}
+
+	// Property names compacted to the same term share the values.
+	properties := make(map[string][]interface{})
+	for _, name := range item.propertyNames() {
+		term := e.term(name)
+		for _, value := range item.Properties[name] {
+			v, err := e.encodeValue(value)
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				properties[term] = append(properties[term], v)
+			}
+		}
+	}
+
+	for term, values := range properties {
+		if e.opts.CollapseSingle && len(values) == 1 {
+			obj[term] = values[0]
+		} else {
+			obj[term] = values
+		}
+	}
+
+	return obj, nil
+}
+
+// encodeValue returns the JSON-LD value of the given property value.
+func (e *jsonldEncoder) encodeValue(value *Value) (interface{}, error) {
+	switch value.Kind {
+	case ItemValue:
+		if value.Item == nil {
+			return nil, nil
+		}
+		return e.encodeItem(value.Item)
+	case NumberValue:
+		n := json.Number(strings.TrimSpace(value.Raw))
+		if _, err := n.Float64(); err == nil && json.Valid([]byte(n)) {
+			return n, nil
+		}
+	}
+	return value.Raw, nil
+}
+
+// term returns the term of the given schema.org IRI when the context is
+// schema.org. Other IRIs and names are returned unchanged.
+func (e *jsonldEncoder) term(iri string) string {
+	if !e.compact {
+		return iri
+	}
+	for _, prefix := range schemaOrgPrefixes {
+		if strings.HasPrefix(iri, prefix) && len(iri) > len(prefix) {
+			return iri[len(prefix):]
+		}
+	}
+	return iri
+}
@@ -0,0 +1,290 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var jsonldSnippet = `
+<html>
+	<head>
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org",
+			"@type": "Product",
+			"@id": "/products/anvil",
+			"name": "Executive Anvil",
+			"image": ["https://example.com/anvil.jpg"],
+			"offers": {
+				"@type": "Offer",
+				"price": 119.99,
+				"priceCurrency": "USD",
+				"priceValidUntil": "2020-11-20",
+				"seller": {"@id": "https://example.com/#org"}
+			}
+		}
+		</script>
+		<script type="application/ld+json; charset=utf-8">
+		{
+			"@context": {"@vocab": "http://schema.org/", "ex": "http://example.com/vocab#"},
+			"@graph": [
+				{"@type": "Organization", "@id": "https://example.com/#org", "name": "ACME"},
+				{"@type": ["WebSite", "ex:Shop"], "url": "https://example.com/", "inLanguage": null}
+			]
+		}
+		</script>
+		<script type="application/ld+json">{"@type": "Broken",</script>
+		<script type="text/javascript">{"@type": "Ignored"}</script>
+	</head>
+</html>`
+
+func TestParseJSONLD(t *testing.T) {
+	u, _ := url.Parse("http://example.com/catalogue")
+	data, err := ParseJSONLD(strings.NewReader(jsonldSnippet), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[` +
+		`{"type":["https://schema.org/Product"],"properties":{"image":["https://example.com/anvil.jpg"],"name":["Executive Anvil"],"offers":[{"type":["https://schema.org/Offer"],"properties":{"price":["119.99"],"priceCurrency":["USD"],"priceValidUntil":["2020-11-20"],"seller":["https://example.com/#org"]}}]},"id":"http://example.com/products/anvil"},` +
+		`{"type":["http://schema.org/Organization"],"properties":{"name":["ACME"]},"id":"https://example.com/#org"},` +
+		`{"type":["http://schema.org/WebSite","http://example.com/vocab#Shop"],"properties":{"url":["https://example.com/"]}}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if len(data.Diagnostics) != 1 || data.Diagnostics[0].Code != DiagInvalidJSONLD {
+		t.Errorf("Diagnostics should have reported \"%s\", but they were \"%v\"", DiagInvalidJSONLD, data.Diagnostics)
+	}
+}
+
+func TestParseJSONLDValueKind(t *testing.T) {
+	u, _ := url.Parse("http://example.com/catalogue")
+	data, err := ParseJSONLD(strings.NewReader(jsonldSnippet), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		path string
+		kind ValueKind
+	}{
+		{"name", TextValue},
+		{"image", URLValue},
+		{"offers", ItemValue},
+		{"offers.price", NumberValue},
+		{"offers.priceValidUntil", DateTimeValue},
+		{"offers.seller", URLValue},
+	}
+
+	for _, test := range testTable {
+		if result := data.Items[0].Get(test.path).Kind; result != test.kind {
+			t.Errorf("Result should have been \"%s\" for \"%s\", but it was \"%s\"", test.kind, test.path, result)
+		}
+	}
+}
+
+func TestParseJSONLDValueObject(t *testing.T) {
+	html := `
+		<script type="application/ld+json">
+		{
+			"@context": {"@vocab": "http://schema.org/", "xsd": "http://www.w3.org/2001/XMLSchema#"},
+			"@type": "Event",
+			"startDate": {"@value": "20:00", "@type": "xsd:time"},
+			"performer": {"@list": [{"@type": "Person", "name": "Ann"}, {"@type": "Person", "name": "Bob"}]}
+		}
+		</script>`
+
+	data, err := ParseJSONLD(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := data.Items[0]
+	if value := item.Get("startDate"); value.Kind != DateTimeValue || value.Raw != "20:00" {
+		t.Errorf("Result should have been a datetime value \"20:00\", but it was \"%s\" \"%s\"", value.Kind, value.Raw)
+	}
+	if result := len(item.GetItems("performer")); result != 2 {
+		t.Errorf("Result should have been \"%d\", but it was \"%d\"", 2, result)
+	}
+}
+
+func TestMarshalJSONLD(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Product" itemid="http://example.com/anvil">
+			<span itemprop="name">Anvil</span>
+			<span itemprop="http://schema.org/category">Tools</span>
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<data itemprop="price" value="119.99">$119.99</data>
+				<meta itemprop="priceCurrency" content="USD">
+				<link itemprop="availability" href="http://schema.org/InStock">
+			</div>
+			<span itemprop="color">black</span>
+			<span itemprop="color">grey</span>
+		</div>`
+
+	data, err := ParseHTML(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		opts     JSONLDOptions
+		expected string
+	}{
+		{
+			JSONLDOptions{},
+			`{"@context":"https://schema.org","@id":"http://example.com/anvil","@type":"Product",` +
+				`"category":["Tools"],"color":["black","grey"],"name":["Anvil"],` +
+				`"offers":[{"@type":"Offer","availability":["http://schema.org/InStock"],"price":[119.99],"priceCurrency":["USD"]}]}`,
+		},
+		{
+			JSONLDOptions{CollapseSingle: true},
+			`{"@context":"https://schema.org","@id":"http://example.com/anvil","@type":"Product",` +
+				`"category":"Tools","color":["black","grey"],"name":"Anvil",` +
+				`"offers":{"@type":"Offer","availability":"http://schema.org/InStock","price":119.99,"priceCurrency":"USD"}}`,
+		},
+		{
+			JSONLDOptions{Context: "http://example.com/context.jsonld", CollapseSingle: true},
+			`{"@context":"http://example.com/context.jsonld","@id":"http://example.com/anvil","@type":"http://schema.org/Product",` +
+				`"color":["black","grey"],"http://schema.org/category":"Tools","name":"Anvil",` +
+				`"offers":{"@type":"https://schema.org/Offer","availability":"http://schema.org/InStock","price":119.99,"priceCurrency":"USD"}}`,
+		},
+	}
+
+	for _, test := range testTable {
+		b, err := data.MarshalJSONLD(test.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := string(b); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+}
+
+func TestMarshalJSONLDGraph(t *testing.T) {
+	a := NewItem()
+	a.addType("http://schema.org/Person")
+	a.ID = "http://example.com/a"
+	b := NewItem()
+	b.addType("http://schema.org/Person")
+	b.addValue("knows", &Value{Kind: ItemValue, Item: a})
+	a.addValue("knows", &Value{Kind: ItemValue, Item: b})
+
+	data := &Microdata{Items: []*Item{a, NewItem()}}
+	result, err := data.MarshalJSONLD(JSONLDOptions{CollapseSingle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"@context":"https://schema.org","@graph":[` +
+		`{"@id":"http://example.com/a","@type":"Person","knows":{"@type":"Person","knows":{"@id":"http://example.com/a"}}},{}]}`
+	if string(result) != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	// Starting from b, the item met again is b, which has no ID.
+	data = &Microdata{Items: []*Item{b}}
+	if _, err := data.MarshalJSONLD(JSONLDOptions{}); err != errCyclicItem {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", errCyclicItem, err)
+	}
+}
+
+func TestMarshalJSONLDRoundTrip(t *testing.T) {
+	data, err := ParseJSONLD(strings.NewReader(jsonldSnippet), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := data.MarshalJSONLD(JSONLDOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := `<script type="application/ld+json">` + string(b) + `</script>`
+	result, err := ParseJSONLD(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Items) != len(data.Items) {
+		t.Errorf("Result should have had %d items, but it had %d", len(data.Items), len(result.Items))
+	}
+	for i := range data.Items {
+		// Compacted types lose the scheme of schema.org IRIs, so only the
+		// property names are compared.
+		if !reflect.DeepEqual(result.Items[i].propertyNames(), data.Items[i].propertyNames()) {
+			t.Errorf("Result should have had properties \"%v\", but it had \"%v\"", data.Items[i].propertyNames(), result.Items[i].propertyNames())
+		}
+	}
+}
+
+func TestParseJSONLDLimits(t *testing.T) {
+	html := `
+		<script type="application/ld+json">
+		[
+			{"@type": "Thing", "name": "Long name", "alternateName": ["A", "B"], "subjectOf": {"@type": "Thing"}},
+			{"@type": "Thing"}
+		]
+		</script>`
+
+	var reported []Diagnostic
+	data, err := ParseJSONLDWith(strings.NewReader(html),
+		WithLimits(Limits{MaxItems: 1, MaxProperties: 2, MaxValueLength: 4}),
+		WithDiagnostics(func(d Diagnostic) { reported = append(reported, d) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Items) != 1 {
+		t.Fatalf("Result should have had 1 item, but it had %d", len(data.Items))
+	}
+	// The properties are read in order: alternateName, name, subjectOf.
+	item := data.Items[0]
+	if len(item.GetAll("alternateName")) != 2 || item.Properties["name"] != nil || item.Properties["subjectOf"] != nil {
+		t.Errorf("Result should have had 2 alternate names only, but it had %v", item.Properties)
+	}
+
+	// A skipped property and a skipped item.
+	if len(reported) != 2 {
+		t.Errorf("Result should have had 2 reported diagnostics, but it had %v", reported)
+	}
+	for _, d := range reported {
+		if d.Code != DiagLimitExceeded || d.Line != 2 {
+			t.Errorf("Result should have been a limit diagnostic at line 2, but it was %v", d)
+		}
+	}
+
+	data, err = ParseJSONLDWith(strings.NewReader(html), WithLimits(Limits{MaxDepth: 1, MaxValueLength: 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := data.Items[0]; item.GetString("name") != "Long" || item.Properties["subjectOf"] != nil {
+		t.Errorf("Result should have had a truncated name and no nested item, but it had %v", item.Properties)
+	}
+	if len(data.Diagnostics) != 2 {
+		t.Errorf("Result should have had 2 diagnostics, but it had %v", data.Diagnostics)
+	}
+}
+
+func TestParseJSONLDWithBaseURL(t *testing.T) {
+	html := `<script type="application/ld+json">{"@id": "anvil", "name": "Anvil"}</script>`
+
+	u, _ := url.Parse("http://example.com/catalogue/")
+	data, err := ParseJSONLDWith(strings.NewReader(html), WithBaseURL(u), WithContentType("text/html; charset=utf-8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := data.Items[0].ID; id != "http://example.com/catalogue/anvil" {
+		t.Errorf("Result should have been \"http://example.com/catalogue/anvil\", but it was \"%s\"", id)
+	}
+}
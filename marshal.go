@@ -0,0 +1,443 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal returns the HTML microdata encoding of v. The value v is either an
+// *Item, a *Microdata or a struct, or pointer to a struct, that is converted
+// to an item with MarshalItem.
+//
+// Parsing the result with ParseHTML yields items with the same types, ids,
+// property names, value kinds and raw values as v. See RenderHTML for the
+// elements used for each value, and for the items it rejects.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch v := v.(type) {
+	case *Microdata:
+		if err := v.RenderHTML(&buf); err != nil {
+			return nil, err
+		}
+	case *Item:
+		if err := RenderHTML(&buf, v); err != nil {
+			return nil, err
+		}
+	default:
+		item, err := MarshalItem(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := RenderHTML(&buf, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// RenderHTML writes the item as microdata annotated HTML to w. The item is
+// rendered as a div element with itemscope, itemtype and itemid attributes,
+// holding one element per property value:
+//
+//	text      <meta itemprop="name" content="...">
+//	url       <link itemprop="url" href="...">
+//	datetime  <time itemprop="startDate" datetime="...">...</time>
+//	number    <data itemprop="price" value="...">...</data>, or meter
+//	item      <div itemprop="offers" itemscope ...>...</div>
+//
+// Properties are rendered sorted by name. As parsers drop empty values and
+// the ids of items without types, RenderHTML returns an error for a value
+// other than an item with an empty raw value, and for an item with an id but
+// no types. It returns an error for a number value whose raw value is not a
+// number as well, which would be read back as text.
+func RenderHTML(w io.Writer, item *Item) error {
+	r := &renderer{
+		w:       bufio.NewWriter(w),
+		pending: make(map[*Item]bool),
+	}
+	if err := r.item(item, "", 0); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// RenderHTML writes the items as microdata annotated HTML to w. See the
+// RenderHTML function for the output format.
+func (m *Microdata) RenderHTML(w io.Writer) error {
+	for _, item := range m.Items {
+		if err := RenderHTML(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errCyclicItem is returned when rendering an item that contains itself.
+var errCyclicItem = errors.New("microdata: cannot render an item that contains itself")
+
+type renderer struct {
+	w *bufio.Writer
+	// pending holds the items being rendered, to detect cycles.
+	pending map[*Item]bool
+}
+
+// item renders the given item, as value of the given property unless the
+// property is empty.
+func (r *renderer) item(item *Item, property string, depth int) error {
+	if item == nil {
+		return nil
+	}
+	if r.pending[item] {
+		return errCyclicItem
+	}
+	if item.ID != "" && len(item.Types) == 0 {
+		return fmt.Errorf("microdata: cannot render id %q of an item without types", item.ID)
+	}
+	r.pending[item] = true
+	defer delete(r.pending, item)
+
+	r.indent(depth)
+	r.w.WriteString("<div")
+	if property != "" {
+		r.attr("itemprop", property)
+	}
+	r.w.WriteString(" itemscope")
+	if len(item.Types) > 0 {
+		r.attr("itemtype", strings.Join(item.Types, " "))
+	}
+	if item.ID != "" {
+		r.attr("itemid", item.ID)
+	}
+	r.w.WriteString(">\n")
+
+	for _, name := range item.propertyNames() {
+		for _, value := range item.Properties[name] {
+			if err := r.value(value, name, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	r.indent(depth)
+	r.w.WriteString("</div>\n")
+	return nil
+}
+
+// value renders the given value of the given property.
+func (r *renderer) value(value *Value, property string, depth int) error {
+	if value == nil {
+		return nil
+	}
+
+	if value.Kind != ItemValue && value.Raw == "" {
+		return fmt.Errorf("microdata: cannot render empty value of property %q", property)
+	}
+
+	switch value.Kind {
+	case ItemValue:
+		return r.item(value.Item, property, depth)
+	case URLValue:
+		r.indent(depth)
+		r.w.WriteString("<link")
+		r.attr("itemprop", property)
+		r.attr("href", value.Raw)
+		r.w.WriteString(">\n")
+	case DateTimeValue:
+		r.element("time", "datetime", value.Raw, property, depth)
+	case NumberValue:
+		if !isNumber(value.Raw) {
+			return fmt.Errorf("microdata: cannot render non-numeric number %q of property %q", value.Raw, property)
+		}
+		element := "data"
+		if value.Element == "meter" {
+			element = "meter"
+		}
+		r.element(element, "value", value.Raw, property, depth)
+	default:
+		r.indent(depth)
+		r.w.WriteString("<meta")
+		r.attr("itemprop", property)
+		r.attr("content", value.Raw)
+		r.w.WriteString(">\n")
+	}
+	return nil
+}
+
+// element renders an element holding the value in the given attribute and as
+// text content.
+func (r *renderer) element(element, attr, value, property string, depth int) {
+	r.indent(depth)
+	r.w.WriteString("<" + element)
+	r.attr("itemprop", property)
+	r.attr(attr, value)
+	r.w.WriteString(">")
+	r.w.WriteString(html.EscapeString(value))
+	r.w.WriteString("</" + element + ">\n")
+}
+
+// attr renders an attribute with an escaped value.
+func (r *renderer) attr(name, value string) {
+	r.w.WriteString(" " + name + `="`)
+	r.w.WriteString(html.EscapeString(value))
+	r.w.WriteString(`"`)
+}
+
+// indent renders the indentation for the given depth.
+func (r *renderer) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		r.w.WriteByte('\t')
+	}
+}
+
+// MarshalItem converts a struct, or pointer to a struct, to an item. It uses
+// the struct tags described for Unmarshal, so that unmarshalling the returned
+// item yields the original struct. Fields with the "omitempty" option are
+// omitted when they hold the zero value of their type. Nil pointers, nil
+// interfaces, empty slices and empty strings never produce a value, as
+// parsers drop empty values.
+//
+// Strings and booleans become text values, numbers become number values,
+// time.Time values datetime values, url.URL values URL values, time.Duration
+// values ISO 8601 durations and structs nested items. Fields implementing
+// encoding.TextMarshaler become text values.
+func MarshalItem(v interface{}) (*Item, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("microdata: cannot marshal %T as item", v)
+	}
+
+	e := &encoder{pending: make(map[uintptr]bool)}
+	return e.item(rv)
+}
+
+type encoder struct {
+	// pending holds the addresses of the pointers being encoded, to detect
+	// cycles.
+	pending map[uintptr]bool
+}
+
+// item converts the given struct value to an item.
+func (e *encoder) item(rv reflect.Value) (*Item, error) {
+	item := NewItem()
+
+	for _, f := range cachedFields(rv.Type()) {
+		fv := rv.FieldByIndex(f.index)
+		switch {
+		case f.itemType:
+			switch {
+			case fv.Kind() == reflect.String && fv.Len() > 0:
+				item.addType(fv.String())
+			case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+				for i := 0; i < fv.Len(); i++ {
+					item.addType(fv.Index(i).String())
+				}
+			}
+			if f.name != "" && !item.HasType(f.name) {
+				item.addType(f.name)
+			}
+		case f.itemID:
+			if fv.Kind() == reflect.String {
+				item.ID = fv.String()
+			}
+		default:
+			if f.omitEmpty && isEmptyValue(fv) {
+				continue
+			}
+			values, err := e.values(fv)
+			if err != nil {
+				return nil, err
+			}
+			for _, value := range values {
+				item.addValue(f.name, value)
+			}
+		}
+	}
+
+	return item, nil
+}
+
+// values converts the given field value to property values.
+func (e *encoder) values(rv reflect.Value) (ValueList, error) {
+	if rv.Type() == valueListType {
+		return rv.Interface().(ValueList), nil
+	}
+
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		var values ValueList
+		for i := 0; i < rv.Len(); i++ {
+			value, err := e.value(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if present(value) {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+
+	value, err := e.value(rv)
+	if err != nil || !present(value) {
+		return nil, err
+	}
+	return ValueList{value}, nil
+}
+
+// present reports whether the value survives parsing: items, and other values
+// with a raw value.
+func present(value *Value) bool {
+	return value != nil && (value.Kind == ItemValue || value.Raw != "")
+}
+
+// value converts the given Go value to a property value. It returns nil for
+// nil pointers and interfaces.
+func (e *encoder) value(rv reflect.Value) (*Value, error) {
+	switch rv.Type() {
+	case valueType:
+		value := rv.Interface().(Value)
+		return &value, nil
+	case reflect.PtrTo(valueType):
+		return rv.Interface().(*Value), nil
+	case reflect.PtrTo(itemType):
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return &Value{Kind: ItemValue, Item: rv.Interface().(*Item)}, nil
+	case timeType:
+		return &Value{Kind: DateTimeValue, Raw: formatTime(rv.Interface().(time.Time))}, nil
+	case durationType:
+		return &Value{Kind: TextValue, Raw: formatDuration(time.Duration(rv.Int()))}, nil
+	case urlType:
+		u := rv.Interface().(url.URL)
+		return &Value{Kind: URLValue, Raw: u.String()}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Ptr {
+			ptr := rv.Pointer()
+			if e.pending[ptr] {
+				return nil, errCyclicItem
+			}
+			e.pending[ptr] = true
+			defer delete(e.pending, ptr)
+		}
+		return e.value(rv.Elem())
+	}
+
+	if m, ok := rv.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Kind: TextValue, Raw: string(b)}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return &Value{Kind: TextValue, Raw: rv.String()}, nil
+	case reflect.Bool:
+		return &Value{Kind: TextValue, Raw: strconv.FormatBool(rv.Bool())}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Value{Kind: NumberValue, Raw: strconv.FormatInt(rv.Int(), 10)}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Value{Kind: NumberValue, Raw: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Value{Kind: NumberValue, Raw: strconv.FormatFloat(rv.Float(), 'f', -1, rv.Type().Bits())}, nil
+	case reflect.Struct:
+		item, err := e.item(rv)
+		if err != nil {
+			return nil, err
+		}
+		return &Value{Kind: ItemValue, Item: item}, nil
+	}
+
+	return nil, fmt.Errorf("microdata: cannot marshal value of type %s", rv.Type())
+}
+
+// isEmptyValue reports whether the given value is the zero value of its type,
+// or an empty slice, map or string.
+func isEmptyValue(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return rv.IsNil()
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			return rv.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
+
+// formatTime formats the time as date when it is midnight UTC, and as date
+// and time otherwise.
+func formatTime(t time.Time) string {
+	if t.Location() == time.UTC && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatDuration formats the duration as ISO 8601 duration.
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var buf strings.Builder
+	if d < 0 {
+		buf.WriteByte('-')
+		d = -d
+	}
+	buf.WriteByte('P')
+	if days := d / (24 * time.Hour); days > 0 {
+		buf.WriteString(strconv.FormatInt(int64(days), 10) + "D")
+		d -= days * 24 * time.Hour
+	}
+	if d > 0 {
+		buf.WriteByte('T')
+		if hours := d / time.Hour; hours > 0 {
+			buf.WriteString(strconv.FormatInt(int64(hours), 10) + "H")
+			d -= hours * time.Hour
+		}
+		if minutes := d / time.Minute; minutes > 0 {
+			buf.WriteString(strconv.FormatInt(int64(minutes), 10) + "M")
+			d -= minutes * time.Minute
+		}
+		if d > 0 {
+			buf.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S")
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,226 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	var testTable = []struct {
+		name    string
+		html    string
+		baseURL string
+	}{
+		{"book", bookSnippet, ""},
+		{"gallery", gallerySnippet, ""},
+		{"blog", blogSnippet, "http://blog.example.com/progress-report"},
+		{"product", productSnippet, "http://example.com"},
+		{"recipe", recipeSnippet, "http://example.com"},
+		{"stackoverflow", stackOverflowSnippet, "http://blog.example.com/progress-report"},
+	}
+
+	for _, test := range testTable {
+		u, _ := url.Parse(test.baseURL)
+		data, err := ParseHTML(bytes.NewBufferString(test.html), "charset=utf-8", u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ParseHTML(bytes.NewReader(b), "charset=utf-8", u)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertEqualMicrodata(t, test.name, data, result)
+	}
+}
+
+// assertEqualMicrodata checks that both microdata have the same items,
+// including the kinds of their values.
+func assertEqualMicrodata(t *testing.T, name string, expected, result *Microdata) {
+	expectedJSON, _ := json.Marshal(expected)
+	resultJSON, _ := json.Marshal(result)
+	if !bytes.Equal(expectedJSON, resultJSON) {
+		t.Errorf("%s: Result should have been \"%s\", but it was \"%s\"", name, expectedJSON, resultJSON)
+		return
+	}
+
+	var compareKinds func(expected, result *Item)
+	compareKinds = func(expected, result *Item) {
+		for property, values := range expected.Properties {
+			for i, value := range values {
+				if kind := result.Properties[property][i].Kind; kind != value.Kind {
+					t.Errorf("%s: Result should have been \"%s\" for property \"%s\", but it was \"%s\"", name, value.Kind, property, kind)
+				}
+				if value.Kind == ItemValue {
+					compareKinds(value.Item, result.Properties[property][i].Item)
+				}
+			}
+		}
+	}
+	for i := range expected.Items {
+		compareKinds(expected.Items[i], result.Items[i])
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	item := NewItem()
+	item.addType("http://schema.org/Event")
+	item.ID = "http://example.com/events/1"
+	item.addValue("name", &Value{Kind: TextValue, Raw: `Rock & "Roll"`})
+	item.addValue("url", &Value{Kind: URLValue, Raw: "http://example.com/events/1"})
+	item.addValue("startDate", &Value{Kind: DateTimeValue, Raw: "2015-07-19T20:00"})
+	location := NewItem()
+	location.addValue("maximumAttendeeCapacity", &Value{Kind: NumberValue, Raw: "400"})
+	item.addValue("location", &Value{Kind: ItemValue, Item: location})
+
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, item); err != nil {
+		t.Fatal(err)
+	}
+
+	result := buf.String()
+	expected := `<div itemscope itemtype="http://schema.org/Event" itemid="http://example.com/events/1">
+	<div itemprop="location" itemscope>
+		<data itemprop="maximumAttendeeCapacity" value="400">400</data>
+	</div>
+	<meta itemprop="name" content="Rock &amp; &#34;Roll&#34;">
+	<time itemprop="startDate" datetime="2015-07-19T20:00">2015-07-19T20:00</time>
+	<link itemprop="url" href="http://example.com/events/1">
+</div>
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestRenderHTMLCycle(t *testing.T) {
+	item := NewItem()
+	item.addValue("self", &Value{Kind: ItemValue, Item: item})
+
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, item); err != errCyclicItem {
+		t.Errorf("Error should have been \"%v\", but it was \"%v\"", errCyclicItem, err)
+	}
+}
+
+func TestRenderHTMLRejects(t *testing.T) {
+	empty := NewItem()
+	empty.addValue("name", &Value{Kind: TextValue})
+	untyped := NewItem()
+	untyped.ID = "http://example.com/1"
+	nested := NewItem()
+	nested.addValue("about", &Value{Kind: ItemValue, Item: untyped})
+	number := NewItem()
+	number.addValue("width", &Value{Kind: NumberValue, Raw: "400px"})
+
+	for name, item := range map[string]*Item{"empty": empty, "untyped": untyped, "nested": nested, "number": number} {
+		if _, err := Marshal(item); err == nil {
+			t.Errorf("%s: Error should have been returned", name)
+		}
+	}
+}
+
+func TestMarshalStructRoundTrip(t *testing.T) {
+	type event struct {
+		ID       string        `microdata:",itemid"`
+		Name     string        `microdata:"name"`
+		Tags     []string      `microdata:"tag"`
+		Duration time.Duration `microdata:"duration"`
+	}
+	e := event{Tags: []string{"", "a"}, Duration: -90 * time.Second}
+
+	b, err := Marshal(&e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ParseHTML(bytes.NewReader(b), "charset=utf-8", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, err := MarshalItem(&e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEqualMicrodata(t, "event", &Microdata{Items: []*Item{item}}, data)
+
+	var result event
+	if err := Unmarshal(data.Items[0], &result); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (event{Tags: []string{"a"}, Duration: e.Duration}); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%+v\", but it was \"%+v\"", expected, result)
+	}
+
+	e.ID = "http://example.com/1"
+	if _, err := Marshal(&e); err == nil {
+		t.Error("Error should have been returned for an id without types")
+	}
+}
+
+func TestMarshalStruct(t *testing.T) {
+	recipe := testRecipe{
+		Type:         []string{"http://schema.org/Recipe"},
+		ID:           "http://example.com/pancakes",
+		Name:         "Pancakes",
+		Published:    time.Date(2015, 7, 19, 0, 0, 0, 0, time.UTC),
+		CookTime:     90 * time.Minute,
+		Servings:     4,
+		Instructions: []string{"Mix.", "Fry."},
+		Author:       testPerson{Type: "http://schema.org/Person", Name: "Penelope"},
+	}
+
+	b, err := Marshal(&recipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("http://example.com")
+	data, err := ParseHTML(bytes.NewReader(b), "charset=utf-8", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Items[0].Get("aggregateRating") != nil {
+		t.Error("Result should have omitted the empty aggregateRating")
+	}
+
+	var result testRecipe
+	if err := Unmarshal(data.Items[0], &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, recipe) {
+		t.Errorf("Result should have been \"%+v\", but it was \"%+v\"", recipe, result)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	var testTable = []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "PT0S"},
+		{30 * time.Minute, "PT30M"},
+		{26*time.Hour + 90*time.Second, "P1DT2H1M30S"},
+		{1500 * time.Millisecond, "PT1.5S"},
+		{-90 * time.Second, "-PT1M30S"},
+	}
+
+	for _, test := range testTable {
+		if result := formatDuration(test.d); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+}
@@ -17,19 +17,38 @@
 		data, _ := microdata.ParseURL("http://example.com/blogposting")
 		items := data.Items
 
+	Pass a context and an URL to the ParseURLContext function to control
+	cancellation and the HTTP client used.
+		client := &http.Client{Timeout: 10 * time.Second}
+		data, err := microdata.ParseURLContext(ctx, "http://example.com/blogposting",
+			microdata.WithHTTPClient(client))
+
 	Pass html Node to the ParseHTMLTree function.
 		parser := microdata.ParseHTMLTree(node, baseURL)
 		items := data.Items
+
+	Pass options to the Parse and ParseTree functions to configure the parser.
+		data, err := microdata.Parse(reader,
+			microdata.WithBaseURL(baseURL),
+			microdata.WithLimits(microdata.Limits{MaxItems: 1000}),
+			microdata.WithDiagnostics(func(d microdata.Diagnostic) {
+				log.Println(d)
+			}))
 */
 
 package microdata
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
@@ -38,6 +57,9 @@ import (
 
 type Microdata struct {
 	Items []*Item `json:"items"`
+
+	// Diagnostics lists the problems found in the markup while parsing.
+	Diagnostics []Diagnostic `json:"-"`
 }
 
 // addItem adds the item to the items list.
@@ -45,7 +67,8 @@ func (m *Microdata) addItem(item *Item) {
 	m.Items = append(m.Items, item)
 }
 
-type ValueList []interface{}
+// ValueList holds the values of a property.
+type ValueList []*Value
 
 type PropertyMap map[string]ValueList
 
@@ -53,17 +76,14 @@ type Item struct {
 	Types      []string    `json:"type"`
 	Properties PropertyMap `json:"properties"`
 	ID         string      `json:"id,omitempty"`
+	// Position locates the itemscope element of the item in the source. It
+	// is only set when parsing with WithPositions.
+	Position *Position `json:"position,omitempty"`
 }
 
-// addString adds the property, value pair to the properties map. It appends to any
+// addValue adds the property, value pair to the properties map. It appends to any
 // existing property.
-func (i *Item) addString(property, value string) {
-	i.Properties[property] = append(i.Properties[property], value)
-}
-
-// addItem adds the property, value pair to the properties map. It appends to any
-// existing property.
-func (i *Item) addItem(property string, value *Item) {
+func (i *Item) addValue(property string, value *Value) {
 	i.Properties[property] = append(i.Properties[property], value)
 }
 
@@ -85,168 +105,502 @@ type parser struct {
 	data            *Microdata
 	baseURL         *url.URL
 	identifiedNodes map[string]*html.Node
+
+	// items holds the converted items by their itemscope element.
+	items map[*html.Node]*Item
+	// pending holds the itemscope elements of the items being converted.
+	pending map[*html.Node]bool
+	// treeOrder holds the position of each node in tree order.
+	treeOrder map[*html.Node]int
+	// located holds the positions of the elements in the source the tree
+	// was parsed from, if available.
+	located map[*html.Node]Position
+	// positions holds the positions of the elements in the source, when
+	// parsing with WithPositions.
+	positions map[*html.Node]Position
+	// withPositions records the positions of the items and values.
+	withPositions bool
+	// limits caps the work done by the parser.
+	limits Limits
+	// itemCount is the number of items converted.
+	itemCount int
+	// report, when set, is called with each diagnostic, once located.
+	report func(Diagnostic)
+	// strict follows the WHATWG algorithm exactly; see WithStrictMode.
+	strict bool
+}
+
+// configure applies the given configuration to the parser.
+func (p *parser) configure(cfg *parseConfig) {
+	p.withPositions = cfg.positions
+	p.limits = cfg.limits
+	p.report = cfg.diagnostics
+	p.strict = cfg.strict
 }
 
 // parse returns the microdata from the parser's node tree.
 func (p *parser) parse() (*Microdata, error) {
 	toplevelNodes := []*html.Node{}
 
+	p.baseURL = documentBaseURL(p.tree, p.baseURL)
+
 	walkNodes(p.tree, func(n *html.Node) {
+		p.treeOrder[n] = len(p.treeOrder)
 		if _, ok := getAttr("itemscope", n); ok {
 			if _, ok := getAttr("itemprop", n); !ok {
 				toplevelNodes = append(toplevelNodes, n)
 			}
 		}
 		if id, ok := getAttr("id", n); ok {
-			p.identifiedNodes[id] = n
+			// As getElementById, itemref uses the first element with an id.
+			if _, ok := p.identifiedNodes[id]; ok {
+				p.warn(DiagDuplicateID, n, fmt.Sprintf("duplicate id %q; the first element with the id is used", id))
+			} else {
+				p.identifiedNodes[id] = n
+			}
 		}
 	})
 
+	if p.withPositions {
+		p.positions = p.located
+	}
+
 	for _, node := range toplevelNodes {
-		item := NewItem()
+		item := p.readItem(node)
+		if item == nil {
+			break
+		}
 		p.data.addItem(item)
-		p.readAttr(item, node)
-		p.readItem(item, node, true)
 	}
 
+	if p.located != nil {
+		locateDiagnostics(p.located, p.data)
+	}
+	reportDiagnostics(p.report, p.data)
+
 	return p.data, nil
 }
 
-// readItem traverses the given node tree, applying relevant attributes to the
-// given item.
-func (p *parser) readItem(item *Item, node *html.Node, isToplevel bool) {
-	itemprops, hasProp := getAttr("itemprop", node)
-	_, hasScope := getAttr("itemscope", node)
+// itemFrame is an item being converted by readItem.
+type itemFrame struct {
+	node  *html.Node
+	item  *Item
+	props []*html.Node
+	// next is the index of the next property element to read.
+	next int
+	// depth is the nesting depth of the item, starting at 1.
+	depth int
+	// values is the number of property values of the item.
+	values int
+}
 
-	switch {
-	case hasScope && hasProp:
-		subItem := NewItem()
-		p.readAttr(subItem, node)
-		for _, propName := range strings.Split(itemprops, " ") {
-			if len(propName) > 0 {
-				item.addItem(propName, subItem)
+// readItem returns the item of the given itemscope element. Items are only
+// converted once; an item that is reachable from several other items through
+// itemref is shared between them. Nested items are converted using an
+// explicit stack, so deeply nested markup does not exhaust the goroutine
+// stack. The item is nil when the item limit is reached.
+func (p *parser) readItem(root *html.Node) *Item {
+	if item, ok := p.items[root]; ok {
+		return item
+	}
+
+	frame := p.openItem(root, 1)
+	if frame == nil {
+		return nil
+	}
+	stack := []*itemFrame{frame}
+	for {
+		f := stack[len(stack)-1]
+		if f.next == len(f.props) {
+			delete(p.pending, f.node)
+			p.items[f.node] = f.item
+			if stack = stack[:len(stack)-1]; len(stack) == 0 {
+				return f.item
 			}
+			continue
 		}
-		for c := node.FirstChild; c != nil; c = c.NextSibling {
-			p.readItem(subItem, c, false)
-		}
-		return
-	case !hasScope && hasProp:
-		if s := p.getValue(node); len(s) > 0 {
-			for _, propName := range strings.Split(itemprops, " ") {
-				if len(propName) > 0 {
-					item.addString(propName, s)
+
+		prop := f.props[f.next]
+		var value *Value
+		if _, ok := getAttr("itemscope", prop); ok {
+			if p.pending[prop] {
+				p.warn(DiagItemrefCycle, prop, "item contains itself through itemref; property skipped")
+				f.next++
+				continue
+			}
+			nested, ok := p.items[prop]
+			if !ok {
+				if max := p.limits.MaxDepth; max > 0 && f.depth >= max {
+					p.warn(DiagLimitExceeded, prop, fmt.Sprintf("item nested deeper than %d levels; property skipped", max))
+					f.next++
+					continue
+				}
+				// Convert the nested item first, then read the property
+				// again.
+				if nested := p.openItem(prop, f.depth+1); nested != nil {
+					stack = append(stack, nested)
+				} else {
+					f.next++
 				}
+				continue
+			}
+			value = &Value{
+				Kind:    ItemValue,
+				Element: prop.Data,
+				Item:    nested,
 			}
+		} else if value = p.getValue(prop); len(value.Raw) == 0 && !p.strict {
+			f.next++
+			continue
+		}
+		f.next++
+		value.Position = p.position(prop)
+
+		itemprops, _ := getAttr("itemprop", prop)
+		for _, propName := range p.tokens(itemprops) {
+			if !p.validPropertyName(prop, propName) {
+				continue
+			}
+			if max := p.limits.MaxProperties; max > 0 && f.values >= max {
+				p.warn(DiagLimitExceeded, f.node, fmt.Sprintf("item has more than %d property values; remaining properties skipped", max))
+				f.next = len(f.props)
+				break
+			}
+			f.item.addValue(propName, value)
+			f.values++
 		}
-	case hasScope && !hasProp && !isToplevel:
-		return
 	}
+}
+
+// openItem starts the conversion of the item of the given itemscope element,
+// nested at the given depth. It returns nil when the item limit is reached.
+func (p *parser) openItem(node *html.Node, depth int) *itemFrame {
+	if max := p.limits.MaxItems; max > 0 && p.itemCount >= max {
+		if p.itemCount == max {
+			p.warn(DiagLimitExceeded, node, fmt.Sprintf("document has more than %d items; remaining items skipped", max))
+			p.itemCount++
+		}
+		return nil
+	}
+	p.itemCount++
+
+	item := NewItem()
+	item.Position = p.position(node)
+	p.readAttr(item, node)
 
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		p.readItem(item, c, false)
+	f := &itemFrame{node: node, item: item, depth: depth}
+	for _, prop := range p.crawlProperties(node) {
+		if itemprops, _ := getAttr("itemprop", prop); len(p.tokens(itemprops)) == 0 {
+			p.warn(DiagEmptyItemprop, prop, "itemprop attribute has no property names; property skipped")
+			continue
+		}
+		f.props = append(f.props, prop)
 	}
+	p.pending[node] = true
+	return f
 }
 
-// readAttr applies relevant attributes from the given node to the given item.
-func (p *parser) readAttr(item *Item, node *html.Node) {
-	if s, ok := getAttr("itemtype", node); ok {
-		for _, itemtype := range strings.Split(s, " ") {
-			if len(itemtype) > 0 {
-				item.addType(itemtype)
-			}
+// crawlProperties returns the property elements of the item of the given
+// itemscope element, using the crawl algorithm of the WHATWG HTML
+// specification. Every element is visited at most once, so the crawl
+// terminates even when itemref attributes form a cycle.
+func (p *parser) crawlProperties(root *html.Node) []*html.Node {
+	var results, pending []*html.Node
+	memory := map[*html.Node]bool{root: true}
+
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			pending = append(pending, c)
 		}
+	}
 
-		if s, ok := getAttr("itemid", node); ok {
-			if u, err := p.baseURL.Parse(s); err == nil {
-				item.ID = u.String()
+	if s, ok := getAttr("itemref", root); ok {
+		for _, itemref := range p.tokens(s) {
+			if n, ok := p.identifiedNodes[itemref]; ok {
+				pending = append(pending, n)
+			} else {
+				p.warn(DiagUnresolvedItemref, root, fmt.Sprintf("itemref %q does not match the id of any element", itemref))
 			}
 		}
 	}
 
-	if s, ok := getAttr("itemref", node); ok {
-		for _, itemref := range strings.Split(s, " ") {
-			if len(itemref) > 0 {
-				if n, ok := p.identifiedNodes[itemref]; ok && !p.isParentNode(n, node) {
-					p.readItem(item, n, false)
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+
+		if current == root {
+			p.warn(DiagItemrefCycle, current, "itemref leads back to the item; cycle cut")
+			continue
+		}
+		if memory[current] {
+			p.warn(DiagDuplicateItemref, current, "element is reachable more than once through itemref; element skipped")
+			continue
+		}
+		memory[current] = true
+
+		if _, ok := getAttr("itemscope", current); !ok {
+			for c := current.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode {
+					pending = append(pending, c)
 				}
 			}
 		}
+
+		if _, ok := getAttr("itemprop", current); ok {
+			results = append(results, current)
+		}
 	}
+
+	// Properties contributed through itemref are sorted in among the item's
+	// own properties, so values appear in the order they were written.
+	sort.Slice(results, func(i, j int) bool {
+		return p.treeOrder[results[i]] < p.treeOrder[results[j]]
+	})
+
+	return results
 }
 
-// isParentNode checks whether node is nested in potential parent node
-func (p *parser) isParentNode(potentialParentNode, node *html.Node) bool {
-	var n = node
-	for n != nil {
-		if n == potentialParentNode {
-			return true
+// readAttr applies the type and id attributes of the given node to the given
+// item.
+func (p *parser) readAttr(item *Item, node *html.Node) {
+	if s, ok := getAttr("itemtype", node); ok {
+		p.readTypes(item, node, s)
+
+		if s, ok := getAttr("itemid", node); ok {
+			if p.strict {
+				s = strings.Trim(s, asciiWhitespace)
+			}
+			if u, err := p.baseURL.Parse(s); err == nil {
+				item.ID = u.String()
+			} else {
+				p.warn(DiagInvalidURL, node, fmt.Sprintf("itemid %q is not a valid URL", s))
+			}
 		}
+	} else if _, ok := getAttr("itemid", node); ok {
+		p.warn(DiagItemidWithoutItemtype, node, "itemid is ignored on an item without itemtype")
+	}
+}
 
-		n = n.Parent
+// position returns the position of the given element in the source, or nil
+// when positions are not recorded or the element has no start tag.
+func (p *parser) position(node *html.Node) *Position {
+	if pos, ok := p.positions[node]; ok {
+		return &pos
 	}
+	return nil
+}
 
-	return false
+// warn records a diagnostic for the given node.
+func (p *parser) warn(code string, node *html.Node, message string) {
+	p.data.addDiagnostic(Diagnostic{
+		Code:    code,
+		Message: message,
+		Node:    node,
+	})
+}
+
+// resolveURL resolves the URL of a property value against the base URL. It
+// records a diagnostic and returns the empty string when the URL cannot be
+// parsed. In strict mode, surrounding ASCII whitespace is ignored.
+func (p *parser) resolveURL(node *html.Node, s string) string {
+	if p.strict {
+		s = strings.Trim(s, asciiWhitespace)
+	}
+	u, ok := resolveURL(p.baseURL, s)
+	if !ok {
+		p.warn(DiagInvalidURL, node, fmt.Sprintf("%q is not a valid URL", s))
+	}
+	return u
 }
 
 // getValue returns the value of the property, value pair in the given node.
-func (p *parser) getValue(node *html.Node) string {
-	var propValue string
+func (p *parser) getValue(node *html.Node) *Value {
+	propValue := &Value{
+		Kind:    TextValue,
+		Element: node.Data,
+	}
 
 	switch node.DataAtom {
 	case atom.Meta:
 		if value, ok := getAttr("content", node); ok {
-			propValue = value
+			propValue.Raw = value
 		}
 	case atom.Audio, atom.Embed, atom.Iframe, atom.Img, atom.Source, atom.Track, atom.Video:
+		propValue.Kind = URLValue
 		if value, ok := getAttr("src", node); ok {
-			if u, err := p.baseURL.Parse(value); err == nil {
-				propValue = u.String()
-			}
+			propValue.Raw = p.resolveURL(node, value)
 		}
 	case atom.A, atom.Area, atom.Link:
+		propValue.Kind = URLValue
 		if value, ok := getAttr("href", node); ok {
-			if u, err := p.baseURL.Parse(value); err == nil {
-				propValue = u.String()
-			}
+			propValue.Raw = p.resolveURL(node, value)
 		}
 	case atom.Data, atom.Meter:
 		if value, ok := getAttr("value", node); ok {
-			propValue = value
+			propValue.Raw = value
+		}
+		// The value of a data element is machine-readable, but not
+		// necessarily a number, e.g. a product code.
+		if isNumber(propValue.Raw) {
+			propValue.Kind = NumberValue
+		}
+	case atom.Object:
+		if !p.strict {
+			propValue.Raw = p.textValue(node)
+			break
+		}
+		propValue.Kind = URLValue
+		if value, ok := getAttr("data", node); ok {
+			propValue.Raw = p.resolveURL(node, value)
 		}
 	case atom.Time:
+		propValue.Kind = DateTimeValue
 		if value, ok := getAttr("datetime", node); ok {
-			propValue = value
+			propValue.Raw = value
+		} else if p.strict {
+			// The datetime value of a time element without datetime
+			// attribute is its text content.
+			propValue.Raw = p.textContent(node)
 		}
 	default:
-		// The "content" attribute can be found on other tags besides the meta tag.
-		if value, ok := getAttr("content", node); ok {
-			propValue = value
-			break
-		}
+		propValue.Raw = p.textValue(node)
+	}
 
-		var buf bytes.Buffer
-		walkNodes(node, func(n *html.Node) {
-			if n.Type == html.TextNode {
-				buf.WriteString(n.Data)
-			}
-		})
-		propValue = buf.String()
+	if max := p.limits.MaxValueLength; max > 0 && len(propValue.Raw) > max {
+		p.warn(DiagLimitExceeded, node, fmt.Sprintf("value longer than %d bytes; value truncated", max))
+		propValue.Raw = truncate(propValue.Raw, max)
 	}
 
 	return propValue
 }
 
-// newParser returns a parser that converts the content of r to UTF-8 based on the content type of r.
-func newParser(r io.Reader, contentType string, baseURL *url.URL) (*parser, error) {
-	r, err := charset.NewReader(r, contentType)
+// textValue returns the value of an element which is not one of the elements
+// with a specific value attribute: its text content.
+func (p *parser) textValue(node *html.Node) string {
+	// The "content" attribute can be found on other tags besides the meta
+	// tag. The strict parser only reads it on meta elements.
+	if value, ok := getAttr("content", node); ok {
+		if !p.strict {
+			return value
+		}
+		p.warn(DiagIgnoredContent, node, "content attribute is ignored on elements other than meta")
+	}
+	return p.textContent(node)
+}
+
+// textContent returns the text content of the given node.
+func (p *parser) textContent(node *html.Node) string {
+	var buf bytes.Buffer
+	max := p.limits.MaxValueLength
+	walkNodes(node, func(n *html.Node) {
+		// Text past the limit is not collected, as it is truncated.
+		if n.Type == html.TextNode && (max <= 0 || buf.Len() <= max) {
+			buf.WriteString(n.Data)
+		}
+	})
+	return buf.String()
+}
+
+// truncate returns the longest prefix of s of at most n bytes that does not
+// split a UTF-8 sequence.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// newParser returns a parser that converts the content of r to UTF-8 based on
+// the content type of r. The positions of the elements are recorded for the
+// parser when keepSource is set.
+func newParser(r io.Reader, contentType string, baseURL *url.URL, keepSource bool) (*parser, error) {
+	tree, positions, err := parseSource(r, contentType, keepSource)
 	if err != nil {
 		return nil, err
 	}
 
-	tree, err := html.Parse(r)
+	p := newTreeParser(tree, baseURL)
+	p.located = positions
+	return p, nil
+}
+
+// parseTree converts the content of r to UTF-8 based on the given content type
+// and parses it into a node tree. When the content type is equal to "", it is
+// detected using `http.DetectContentType`.
+func parseTree(r io.Reader, contentType string) (*html.Node, error) {
+	tree, _, err := parseSource(r, contentType, false)
+	return tree, err
+}
+
+// parseSource is like parseTree, but when keep is set it also returns the
+// positions of the elements in the UTF-8 source, used to locate diagnostics
+// and record positions, see parsePositions. The source is then held in memory
+// while parsing. Otherwise it is parsed as it is read.
+func parseSource(r io.Reader, contentType string, keep bool) (*html.Node, map[*html.Node]Position, error) {
+	r, err := utf8Reader(r, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !keep {
+		tree, err := html.Parse(r)
+		return tree, nil, err
+	}
+
+	src, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	return parsePositions(src)
+}
+
+// utf8Reader returns a reader converting the content of r to UTF-8 based on
+// the given content type. When the content type is equal to "", it is
+// detected using `http.DetectContentType`.
+func utf8Reader(r io.Reader, contentType string) (io.Reader, error) {
+	if contentType == "" {
+		b := make([]byte, 512)
+		n, err := io.ReadFull(r, b)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		contentType = http.DetectContentType(b[:n])
+		r = io.MultiReader(bytes.NewReader(b[:n]), r)
+	}
+
+	return charset.NewReader(r, contentType)
+}
+
+// documentBaseURL returns the base URL of the document: the href of its first
+// base element resolved against the given URL, as a browser does, or the given
+// URL when there is no such element.
+func documentBaseURL(tree *html.Node, u *url.URL) *url.URL {
+	var base *html.Node
+	walkNodes(tree, func(n *html.Node) {
+		if n.DataAtom == atom.Base && base == nil {
+			if _, ok := getAttr("href", n); ok {
+				base = n
+			}
+		}
+	})
+
+	if base != nil {
+		href, _ := getAttr("href", base)
+		if b, err := u.Parse(strings.TrimSpace(href)); err == nil {
+			return b
+		}
+	}
+	return u
+}
+
+// newTreeParser returns a parser for the given node tree. A nil baseURL is
+// treated as the empty URL.
+func newTreeParser(tree *html.Node, baseURL *url.URL) *parser {
+	if baseURL == nil {
+		baseURL = &url.URL{}
 	}
 
 	return &parser{
@@ -254,7 +608,20 @@ func newParser(r io.Reader, contentType string, baseURL *url.URL) (*parser, erro
 		data:            &Microdata{},
 		baseURL:         baseURL,
 		identifiedNodes: make(map[string]*html.Node),
-	}, nil
+		items:           make(map[*html.Node]*Item),
+		pending:         make(map[*html.Node]bool),
+		treeOrder:       make(map[*html.Node]int),
+	}
+}
+
+// resolveURL resolves the given URL against the base URL. It reports whether
+// the URL could be parsed.
+func resolveURL(base *url.URL, s string) (string, bool) {
+	u, err := base.Parse(s)
+	if err != nil {
+		return "", false
+	}
+	return u.String(), true
 }
 
 // getAttr returns the value associated with the given attribute from the given node.
@@ -267,72 +634,149 @@ func getAttr(attribute string, node *html.Node) (string, bool) {
 	return "", false
 }
 
-// walkNodes traverses the node tree executing the given functions.
+// walkNodes traverses the node tree in tree order executing the given
+// function. It uses an explicit stack, so deeply nested markup does not
+// exhaust the goroutine stack.
 func walkNodes(n *html.Node, f func(*html.Node)) {
-	if n != nil {
+	if n == nil {
+		return
+	}
+	stack := []*html.Node{n}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
 		f(n)
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			walkNodes(c, f)
+		for c := n.LastChild; c != nil; c = c.PrevSibling {
+			stack = append(stack, c)
 		}
 	}
 }
 
-// ParseHTMLTree parses the HTML document passed as an argument
-func ParseHTMLTree(tree *html.Node, u *url.URL) (*Microdata, error) {
-	p := &parser{
-		tree:            tree,
-		data:            &Microdata{},
-		baseURL:         u,
-		identifiedNodes: make(map[string]*html.Node),
+// Parse parses the HTML document available in the given reader and returns
+// the microdata. WithBaseURL sets the URL used to resolve the URLs in the
+// attributes, unless the document contains a base element, and
+// WithContentType the content type used to convert the content of r to
+// UTF-8. WithLimits, WithPositions and WithDiagnostics apply as well.
+func Parse(r io.Reader, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+
+	p, err := newParser(r, cfg.contentType, cfg.baseURL, cfg.keepSource())
+	if err != nil {
+		return nil, err
 	}
+	p.configure(cfg)
+	return p.parse()
+}
+
+// ParseTree parses the given HTML document and returns the microdata. See
+// Parse; the diagnostics have no line and column and WithPositions does not
+// apply, as the source of the document is not available.
+func ParseTree(tree *html.Node, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
 
+	p := newTreeParser(tree, cfg.baseURL)
+	p.configure(cfg)
 	return p.parse()
 }
 
+// ParseHTMLTree parses the HTML document passed as an argument. The given url
+// is used to resolve the URLs in the attributes, unless the document contains
+// a base element. It is equivalent to ParseTree with WithBaseURL.
+func ParseHTMLTree(tree *html.Node, u *url.URL) (*Microdata, error) {
+	return ParseTree(tree, WithBaseURL(u))
+}
+
 // ParseHTML parses the HTML document available in the given reader and returns
-// the microdata. The given url is used to resolve the URLs in the
-// attributes. The given contentType is used convert the content of r to UTF-8.
-// When the given contentType is equal to "", the content type will be detected
-// using `http.DetectContentType`.
+// the microdata. The given url is used to resolve the URLs in the attributes,
+// unless the document contains a base element. The given contentType is used
+// convert the content of r to UTF-8. When the given contentType is equal to "",
+// the content type will be detected using `http.DetectContentType`. It is
+// equivalent to Parse with WithContentType and WithBaseURL.
 func ParseHTML(r io.Reader, contentType string, u *url.URL) (*Microdata, error) {
-	if contentType == "" {
-		b := make([]byte, 512)
-		_, err := r.Read(b)
-		if err != nil {
-			return nil, err
-		}
-		contentType = http.DetectContentType(b)
-		r = io.MultiReader(bytes.NewReader(b), r)
+	return Parse(r, WithContentType(contentType), WithBaseURL(u))
+}
+
+// HTTPError is returned when fetching a document results in a non-2xx
+// response status.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("microdata: fetching %s: unexpected status %s", e.URL, e.Status)
+}
+
+// ParseURL parses the HTML document available at the given URL and returns the
+// microdata. Relative URLs are resolved against the URL the document was
+// eventually retrieved from, after following redirects.
+func ParseURL(urlStr string) (*Microdata, error) {
+	return ParseURLContext(context.Background(), urlStr)
+}
+
+// ParseURLContext parses the HTML document available at the given URL and
+// returns the microdata. The request is bound to ctx, so it is aborted when
+// ctx is cancelled or its deadline expires. A response with a non-2xx status
+// results in an *HTTPError. WithHTTPClient sets the client used; the content
+// type of the response and the URL the document was eventually retrieved from
+// take precedence over WithContentType and WithBaseURL. The other options
+// apply as with Parse.
+func ParseURLContext(ctx context.Context, urlStr string, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+
+	resp, u, err := fetch(ctx, cfg.fetcher, urlStr)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	p, err := newParser(r, contentType, u)
+	contentType := cfg.contentType
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		contentType = ct
+	}
+
+	p, err := newParser(resp.Body, contentType, u, cfg.keepSource())
 	if err != nil {
 		return nil, err
 	}
+	p.configure(cfg)
+
 	return p.parse()
 }
 
-// ParseURL parses the HTML document available at the given URL and returns the
-// microdata.
-func ParseURL(urlStr string) (*Microdata, error) {
-	var data *Microdata
-
+// fetch retrieves the document at the given URL with the given fetcher. It
+// returns the response, whose body the caller must close, and the URL the
+// document was eventually retrieved from, after following redirects.
+func fetch(ctx context.Context, fetcher Fetcher, urlStr string) (*http.Response, *url.URL, error) {
 	u, err := url.Parse(urlStr)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	resp, err := http.DefaultClient.Get(urlStr)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
-		return data, err
+		return nil, nil, err
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-
-	p, err := newParser(resp.Body, contentType, u)
+	resp, err := fetcher.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return p.parse()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, nil, &HTTPError{
+			URL:        urlStr,
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+		}
+	}
+
+	// Relative URLs are resolved against the final URL after redirects.
+	if resp.Request != nil && resp.Request.URL != nil {
+		u = resp.Request.URL
+	}
+
+	return resp, u, nil
 }
@@ -5,14 +5,22 @@ package microdata
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/bradleyjkemp/cupaloy"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/net/html"
 )
 
 func TestParseItemScope(t *testing.T) {
@@ -66,7 +74,7 @@ func TestParseItemRef(t *testing.T) {
 	data := ParseData(html, t)
 
 	for _, test := range testTable {
-		if result := data.Items[0].Properties[test.propName][0].(string); result != test.expected {
+		if result := data.Items[0].Properties[test.propName][0].Raw; result != test.expected {
 			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
 		}
 	}
@@ -80,7 +88,7 @@ func TestParseItemProp(t *testing.T) {
 
 	data := ParseData(html, t)
 
-	result := data.Items[0].Properties["name"][0].(string)
+	result := data.Items[0].Properties["name"][0].Raw
 	expected := "Penelope"
 	if result != expected {
 		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
@@ -127,7 +135,7 @@ func TestParseHref(t *testing.T) {
 	data := ParseData(html, t)
 
 	for _, test := range testTable {
-		if result := data.Items[0].Properties[test.propName][0].(string); result != test.expected {
+		if result := data.Items[0].Properties[test.propName][0].Raw; result != test.expected {
 			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
 		}
 	}
@@ -161,7 +169,7 @@ func TestParseSrc(t *testing.T) {
 	data := ParseData(html, t)
 
 	for _, test := range testTable {
-		if result := data.Items[0].Properties[test.propName][0].(string); result != test.expected {
+		if result := data.Items[0].Properties[test.propName][0].Raw; result != test.expected {
 			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
 		}
 	}
@@ -175,7 +183,7 @@ func TestParseMetaContent(t *testing.T) {
 
 	data := ParseData(html, t)
 
-	result := data.Items[0].Properties["length"][0].(string)
+	result := data.Items[0].Properties["length"][0].Raw
 	expected := "1.70"
 	if result != expected {
 		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
@@ -200,7 +208,7 @@ func TestParseValue(t *testing.T) {
 	data := ParseData(html, t)
 
 	for _, test := range testTable {
-		if result := data.Items[0].Properties[test.propName][0].(string); result != test.expected {
+		if result := data.Items[0].Properties[test.propName][0].Raw; result != test.expected {
 			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
 		}
 	}
@@ -214,7 +222,7 @@ func TestParseDatetime(t *testing.T) {
 
 	data := ParseData(html, t)
 
-	result := data.Items[0].Properties["birthDate"][0].(string)
+	result := data.Items[0].Properties["birthDate"][0].Raw
 	expected := "1993-10-02"
 	if result != expected {
 		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
@@ -229,7 +237,7 @@ func TestParseText(t *testing.T) {
 
 	data := ParseData(html, t)
 
-	result := data.Items[0].Properties["price"][0].(string)
+	result := data.Items[0].Properties["price"][0].Raw
 	expected := "3.95"
 	if result != expected {
 		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
@@ -251,6 +259,395 @@ func TestParseMultiItemTypes(t *testing.T) {
 	}
 }
 
+func TestParseBaseHref(t *testing.T) {
+	html := `
+		<html>
+			<head>
+				<base href="/media/">
+				<base href="http://example.org/ignored/">
+			</head>
+			<body>
+				<div itemscope itemtype="http://example.com/Person" itemid="people/penelope">
+					<img itemprop="image" src="penelope.jpg" />
+					<a itemprop="url" href="../about">About</a>
+				</div>
+			</body>
+		</html>`
+
+	var testTable = []struct {
+		propName string
+		expected string
+	}{
+		{"image", "http://example.com/media/penelope.jpg"},
+		{"url", "http://example.com/about"},
+	}
+
+	data := ParseData(html, t)
+
+	for _, test := range testTable {
+		if result := data.Items[0].Properties[test.propName][0].Raw; result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+
+	result := data.Items[0].ID
+	expected := "http://example.com/media/people/penelope"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseHTMLTreeNilURL(t *testing.T) {
+	tree, err := html.Parse(strings.NewReader(`
+		<base href="http://example.com/">
+		<div itemscope>
+			<a itemprop="url" href="about">About</a>
+		</div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ParseHTMLTree(tree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := data.Items[0].Properties["url"][0].Raw
+	expected := "http://example.com/about"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseItemRefTreeOrder(t *testing.T) {
+	html := `
+		<ol id="first-steps">
+			<li itemprop="recipeInstructions">Preheat the oven.</li>
+			<li itemprop="recipeInstructions">Mix the flour and the eggs.</li>
+		</ol>
+		<div itemscope itemtype="http://schema.org/Recipe" itemref="last-step first-steps">
+			<p itemprop="recipeInstructions">Pour the batter into the pan.</p>
+		</div>
+		<p id="last-step" itemprop="recipeInstructions">Bake for 30 minutes.</p>`
+
+	expected := []string{
+		"Preheat the oven.",
+		"Mix the flour and the eggs.",
+		"Pour the batter into the pan.",
+		"Bake for 30 minutes.",
+	}
+
+	data := ParseData(html, t)
+
+	values := data.Items[0].Properties["recipeInstructions"]
+	if len(values) != len(expected) {
+		t.Fatalf("Result should have been \"%d\" values, but it was \"%d\"", len(expected), len(values))
+	}
+	for i, value := range values {
+		if result := value.Raw; result != expected[i] {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected[i], result)
+		}
+	}
+}
+
+func TestParseItemRefCycle(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://example.com/Person" itemref="b">
+			<span itemprop="name">Penelope</span>
+		</div>
+		<div id="b">
+			<div itemprop="knows" itemscope itemtype="http://example.com/Person" itemref="b">
+				<span itemprop="name">Odysseus</span>
+			</div>
+		</div>`
+
+	data := ParseData(html, t)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["http://example.com/Person"],"properties":{"knows":[{"type":["http://example.com/Person"],"properties":{"name":["Odysseus"]}}],"name":["Penelope"]}}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if len(data.Diagnostics) == 0 || data.Diagnostics[0].Code != DiagItemrefCycle {
+		t.Errorf("Diagnostics should have reported \"%s\", but they were \"%v\"", DiagItemrefCycle, data.Diagnostics)
+	}
+}
+
+func TestParseItemRefDescendant(t *testing.T) {
+	html := `
+		<div itemscope itemref="name">
+			<span id="name" itemprop="name">Penelope</span>
+		</div>`
+
+	data := ParseData(html, t)
+
+	if result := stringValues(data.Items[0], "name"); !reflect.DeepEqual(result, []string{"Penelope"}) {
+		t.Errorf("Result should have been [Penelope], but it was %v", result)
+	}
+	if len(data.Diagnostics) != 1 || data.Diagnostics[0].Code != DiagDuplicateItemref {
+		t.Errorf("Diagnostics should have reported \"%s\", but they were \"%v\"", DiagDuplicateItemref, data.Diagnostics)
+	}
+}
+
+func TestParseNestedItemRefCycle(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://example.com/Thing">
+			<div id="x" itemprop="a" itemscope itemref="y"><span itemprop="name">x</span></div>
+			<div id="y" itemprop="b" itemscope itemref="x"><span itemprop="name">y</span></div>
+		</div>`
+
+	data := ParseData(html, t)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["http://example.com/Thing"],"properties":{"a":[{"type":[],"properties":{"b":[{"type":[],"properties":{"name":["y"]}}],"name":["x"]}}],"b":[{"type":[],"properties":{"name":["y"]}}]}}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	if len(data.Diagnostics) != 1 || data.Diagnostics[0].Code != DiagItemrefCycle {
+		t.Errorf("Diagnostics should have reported \"%s\", but they were \"%v\"", DiagItemrefCycle, data.Diagnostics)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	src := "<div itemscope itemref=\"x\"><a itemprop=\"url\" href=\"/caf\xe9\">Caf\xe9</a></div>"
+	u, _ := url.Parse("http://example.com/a/")
+
+	var reported []string
+	data, err := Parse(strings.NewReader(src),
+		WithBaseURL(u),
+		WithContentType("text/html; charset=iso-8859-1"),
+		WithDiagnostics(func(d Diagnostic) {
+			reported = append(reported, d.String())
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := data.Items[0].GetString("url"); result != "http://example.com/caf%C3%A9" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "http://example.com/caf%C3%A9", result)
+	}
+	expected := []string{`1:1: unresolved-itemref: itemref "x" does not match the id of any element`}
+	if !reflect.DeepEqual(reported, expected) {
+		t.Errorf("Reported diagnostics should have been \"%v\", but they were \"%v\"", expected, reported)
+	}
+	if len(data.Diagnostics) != 1 {
+		t.Errorf("Result should have had 1 diagnostic, but it had %d", len(data.Diagnostics))
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	tree, err := html.Parse(strings.NewReader(`<div itemscope><a itemprop="url" href="b">B</a></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("http://example.com/a/")
+
+	data, err := ParseTree(tree, WithBaseURL(u), WithLimits(Limits{MaxValueLength: 20}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := data.Items[0].GetString("url"); result != "http://example.com/a" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "http://example.com/a", result)
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	html := `<html><body>
+<div itemscope itemid="urn:isbn:0-330-34032-8" itemref="missing">
+	<span itemprop="">No name</span>
+	<a itemprop="url" href="http://[::1">Broken</a>
+</div>
+<p id="dup" itemprop="name">First</p>
+<p id="dup">Second</p>
+</body></html>`
+
+	// Without the source, the diagnostics are not located.
+	data := ParseData(html, t)
+	if len(data.Diagnostics) != 5 || data.Diagnostics[0].Line != 0 {
+		t.Errorf("Diagnostics should have been 5 unlocated diagnostics, but they were \"%v\"", data.Diagnostics)
+	}
+
+	data, err := Parse(strings.NewReader(html), WithDiagnostics(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for _, d := range data.Diagnostics {
+		result = append(result, fmt.Sprintf("%s <%s> %s", d.String(), d.Element, d.Code))
+	}
+	expected := []string{
+		`7:1: duplicate-id: duplicate id "dup"; the first element with the id is used <p> duplicate-id`,
+		`2:1: itemid-without-itemtype: itemid is ignored on an item without itemtype <div> itemid-without-itemtype`,
+		`2:1: unresolved-itemref: itemref "missing" does not match the id of any element <div> unresolved-itemref`,
+		`3:2: empty-itemprop: itemprop attribute has no property names; property skipped <span> empty-itemprop`,
+		`4:2: invalid-url: "http://[::1" is not a valid URL <a> invalid-url`,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Diagnostics should have been \"%s\", but they were \"%s\"", strings.Join(expected, "\n"), strings.Join(result, "\n"))
+	}
+}
+
+func TestParseDuplicateID(t *testing.T) {
+	html := `
+		<div itemscope itemref="a"></div>
+		<p id="a" itemprop="name">First</p>
+		<p id="a" itemprop="name">Second</p>`
+
+	data := ParseData(html, t)
+
+	if result := data.Items[0].GetString("name"); result != "First" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "First", result)
+	}
+}
+
+func TestParseHTMLTreeDiagnostics(t *testing.T) {
+	tree, err := html.Parse(strings.NewReader(`<div itemscope itemid="x"></div>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ParseHTMLTree(tree, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Diagnostics) != 1 || data.Diagnostics[0].Line != 0 || data.Diagnostics[0].Element != "div" {
+		t.Errorf("Diagnostics should have had an unlocated div diagnostic, but they were \"%v\"", data.Diagnostics)
+	}
+}
+
+func parseWithLimits(html string, limits Limits, t *testing.T) *Microdata {
+	data, err := Parse(strings.NewReader(html), WithLimits(limits))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseLimits(t *testing.T) {
+	html := `
+		<div itemscope>
+			<p itemprop="name">Jane Doe</p>
+			<p itemprop="a b">Shared</p>
+			<div itemprop="knows" itemscope>
+				<p itemprop="name">John</p>
+				<div itemprop="knows" itemscope><p itemprop="name">Jim</p></div>
+			</div>
+		</div>
+		<div itemscope><p itemprop="name">Second</p></div>
+		<div itemscope><p itemprop="name">Third</p></div>`
+
+	tests := []struct {
+		limits   Limits
+		expected string
+		messages []string
+	}{
+		{
+			Limits{},
+			`{"items":[{"type":[],"properties":{"a":["Shared"],"b":["Shared"],"knows":[{"type":[],"properties":{"knows":[{"type":[],"properties":{"name":["Jim"]}}],"name":["John"]}}],"name":["Jane Doe"]}},{"type":[],"properties":{"name":["Second"]}},{"type":[],"properties":{"name":["Third"]}}]}`,
+			nil,
+		},
+		{
+			Limits{MaxDepth: 2},
+			`{"items":[{"type":[],"properties":{"a":["Shared"],"b":["Shared"],"knows":[{"type":[],"properties":{"name":["John"]}}],"name":["Jane Doe"]}},{"type":[],"properties":{"name":["Second"]}},{"type":[],"properties":{"name":["Third"]}}]}`,
+			[]string{"item nested deeper than 2 levels; property skipped"},
+		},
+		{
+			Limits{MaxItems: 3},
+			`{"items":[{"type":[],"properties":{"a":["Shared"],"b":["Shared"],"knows":[{"type":[],"properties":{"knows":[{"type":[],"properties":{"name":["Jim"]}}],"name":["John"]}}],"name":["Jane Doe"]}}]}`,
+			[]string{"document has more than 3 items; remaining items skipped"},
+		},
+		{
+			Limits{MaxProperties: 2},
+			`{"items":[{"type":[],"properties":{"a":["Shared"],"name":["Jane Doe"]}},{"type":[],"properties":{"name":["Second"]}},{"type":[],"properties":{"name":["Third"]}}]}`,
+			[]string{"item has more than 2 property values; remaining properties skipped"},
+		},
+		{
+			Limits{MaxValueLength: 4},
+			`{"items":[{"type":[],"properties":{"a":["Shar"],"b":["Shar"],"knows":[{"type":[],"properties":{"knows":[{"type":[],"properties":{"name":["Jim"]}}],"name":["John"]}}],"name":["Jane"]}},{"type":[],"properties":{"name":["Seco"]}},{"type":[],"properties":{"name":["Thir"]}}]}`,
+			[]string{
+				"value longer than 4 bytes; value truncated",
+				"value longer than 4 bytes; value truncated",
+				"value longer than 4 bytes; value truncated",
+				"value longer than 4 bytes; value truncated",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		data := parseWithLimits(html, test.limits, t)
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := string(b); result != test.expected {
+			t.Errorf("Result with %+v should have been \"%s\", but it was \"%s\"", test.limits, test.expected, result)
+		}
+
+		var messages []string
+		for _, d := range data.Diagnostics {
+			if d.Code == DiagLimitExceeded {
+				messages = append(messages, d.Message)
+			}
+		}
+		if !reflect.DeepEqual(messages, test.messages) {
+			t.Errorf("Diagnostics with %+v should have been \"%v\", but they were \"%v\"", test.limits, test.messages, messages)
+		}
+	}
+}
+
+func TestParseDeepNesting(t *testing.T) {
+	const depth = 1000
+	html := strings.Repeat(`<div itemprop="child" itemscope>`, depth) + "leaf" + strings.Repeat("</div>", depth)
+	html = `<div itemscope>` + html + `</div>`
+
+	data := parseWithLimits(html, Limits{}, t)
+	n := 0
+	for item := data.Items[0]; item != nil; item = item.GetItem("child") {
+		n++
+	}
+	if n != depth+1 {
+		t.Errorf("Result should have had %d nested items, but it had %d", depth+1, n)
+	}
+
+	data = parseWithLimits(html, Limits{MaxDepth: 10}, t)
+	n = 0
+	for item := data.Items[0]; item != nil; item = item.GetItem("child") {
+		n++
+	}
+	if n != 10 {
+		t.Errorf("Result should have had %d nested items, but it had %d", 10, n)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, test := range []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 2, "ab"},
+		{"aé", 2, "a"},
+		{"aé", 3, "aé"},
+	} {
+		if result := truncate(test.s, test.n); result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+}
+
 func TestJSON(t *testing.T) {
 	html := `
 		<div itemscope itemtype="http://example.com/Person">
@@ -298,20 +695,149 @@ func TestParseURL(t *testing.T) {
 		t.Error(err)
 	}
 
-	result := data.Items[0].Properties["name"][0].(string)
+	result := data.Items[0].Properties["name"][0].Raw
 	expected := "Penelope"
 	if result != expected {
 		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
 	}
 }
 
+func TestParseURLContextHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `<div itemscope><span itemprop="name">Not found</span></div>`, http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	data, err := ParseURLContext(context.Background(), ts.URL)
+	if data != nil {
+		t.Errorf("Result should have been nil, but it was \"%v\"", data)
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("Error should have been an *HTTPError, but it was \"%v\"", err)
+	}
+	if httpErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Result should have been \"%d\", but it was \"%d\"", http.StatusNotFound, httpErr.StatusCode)
+	}
+}
+
+func TestParseURLContextCancel(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := ParseURLContext(ctx, ts.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Error should have been \"%v\", but it was \"%v\"", context.DeadlineExceeded, err)
+	}
+}
+
+type fetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f fetcherFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestParseURLContextFetcher(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader(`
+		<div itemscope itemtype="http://example.com/Person">
+			<p>My name is <span itemprop="name">Penelope</span>.</p>
+		</div>`)}
+
+	fetcher := fetcherFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       body,
+			Request:    req,
+		}, nil
+	})
+
+	data, err := ParseURLContext(context.Background(), "http://example.com/person", WithHTTPClient(fetcher))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := data.Items[0].Properties["name"][0].Raw
+	expected := "Penelope"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+	if !body.closed {
+		t.Error("Response body should have been closed")
+	}
+}
+
+func TestParseURLRedirect(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/old", http.RedirectHandler("/new/person", http.StatusMovedPermanently))
+	mux.HandleFunc("/new/person", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`
+			<div itemscope itemtype="http://example.com/Person">
+				<img itemprop="image" src="penelope.jpg" />
+			</div>`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	data, err := ParseURL(ts.URL + "/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := data.Items[0].Properties["image"][0].Raw
+	expected := ts.URL + "/new/penelope.jpg"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+// TestParseURLSnapshot parses a news article saved in testdata/idnes.html,
+// served for its original URL by the fetcher so the test runs offline.
 func TestParseURLSnapshot(t *testing.T) {
-	data, err := ParseURL("https://www.idnes.cz/zlin/zpravy/smrtelna-nehoda-motorkar-srazil-zenu.A150719_165426_zlin-zpravy_kol")
+	page, err := ioutil.ReadFile("testdata/idnes.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetcher := fetcherFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Status:     "200 OK",
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       ioutil.NopCloser(bytes.NewReader(page)),
+			Request:    req,
+		}, nil
+	})
+
+	data, err := ParseURLContext(context.Background(), "https://www.idnes.cz/zlin/zpravy/smrtelna-nehoda-motorkar-srazil-zenu.A150719_165426_zlin-zpravy_kol", WithHTTPClient(fetcher))
 	if err != nil {
 		t.Error(err)
 	}
 
-	cupaloy.SnapshotT(t, data)
+	// The items are compared in their JSON encoding, which only holds the
+	// types, ids and raw values of the items.
+	b, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cupaloy.SnapshotT(t, string(b))
 }
 
 func TestNestedItems(t *testing.T) {
@@ -343,7 +869,7 @@ func ParseData(html string, t *testing.T) *Microdata {
 	r := strings.NewReader(html)
 	u, _ := url.Parse("http://example.com")
 
-	p, err := newParser(r, "utf-8", u)
+	p, err := newParser(r, "utf-8", u, false)
 	if err != nil {
 		t.Error(err)
 	}
@@ -430,7 +956,7 @@ func TestParseStackOverflowSnippet(t *testing.T) {
 	}
 
 	result := string(b)
-	expected := `{"items":[{"type":["https://schema.org/WebPage"],"properties":{"breadcrumb":["Aktien»Nachrichten»GENERIC GOLD AKTIE»Generic Gold Announces Upsizing of Fully Subscribed Private Placement up to $7 Million"],"image":["https://www.finanznachrichten.de/chart-generic-gold-corp-aktie-1jahrklein-frankfurt.png"]}},{"type":["http://schema.org/Product"],"properties":{"image":["https://www.finanznachrichten.de/chart-generic-gold-corp-aktie-1jahrklein-frankfurt.png"],"name":["GENERIC GOLD CORP"],"offers":[{"type":["http://schema.org/Offer"],"properties":{"price":["0.28"],"priceCurrency":["EUR"]}}],"priceValidUntil":["2020-10-15T09:29:28.0000000"],"productID":["wkn:A2JAE9","wkn:A2JAE9"],"seller":["Frankfurt"],"url":["https://www.finanznachrichten.de/nachrichten-aktien/generic-gold-corp.htm"]}},{"type":["http://schema.org/Article"],"properties":{"aggregateRating":[{"type":["http://schema.org/AggregateRating"],"properties":{"bestRating":["5"],"itemReviewed":["Generic Gold Announces Upsizing of Fully Subscribed Private Placement up to $7 Million"],"ratingCount":["2"],"ratingValue":["4,5"],"worstRating":["1"]}}],"articleBody":["Toronto, Ontario--(Newsfile Corp. - July 27, 2020) - Generic Gold Corp. (CSE: GGC) (FSE: 1WD) (\"Generic Gold\" or the \"Company\") is pleased to announce, further to its press release of July 16, 2020, the upsize of its fully subscribed \"best efforts\" private placement offering, led by StephenAvenue Securities Inc. (the \"Agent\") as sole agent and sole bookrunner (the \"Offering\"), for aggregate gross proceeds of up to $7,000,000, through the issuance of units (each, a \"Unit\") at a price of $0.35 per Unit and flow-through units (each, a \"FT Unit\") at a price of $0.40 per FT Unit (together, the Units and the FT Units, the \"Offered Securities\").The net proceeds from the sale of the Units will be used for general working capital and exploration purposes. The gross proceeds from the sale of the FT Units will be used by the Company to incur eligible \"Canadian exploration expenses\" that will qualify as \"flow-through mining expenditures\" (as such terms are defined in the Income Tax Act (Canada)) (the \"Qualifying Expenditures\") related to the Company's projects in Canada. All Qualifying Expenditures will be renounced in favour of the subscribers of the FT Units effective December 31, 2020. It is anticipated that most of the funds derived from the sale of the FT Units will be used to explore the Company's recently acquired Belvais project which is contiguous to Amex Exploration Inc.  (refer to the Company's press release of July 7, 2020).The Offering is expected to close on or about August 6, 2020 (the \"Closing Date\"), or such other date as agreed between the Company and the Agent. The completion of the Offering is subject to certain closing conditions including, but not limited to, the receipt of all necessary regulatory and other approvals including the approval of the Canadian Securities Exchange. All Offered Securities will be subject to a statutory hold period of four months and one day from the Closing Date.The Offered Securities have not been and will not be registered under the U.S. Securities Act of 1933, as amended, and may not be offered or sold in the United States absent registration or an applicable exemption from the registration requirements. This press release shall not constitute an offer to sell or the solicitation of an offer to buy nor shall there be any sale of the Offered Securities in any State in which such offer, solicitation or sale would be unlawful.About Generic GoldGeneric Gold is a Canadian mineral exploration company focused on gold projects in the Abitibi Greenstone Belt in Quebec, Canada and Tintina Gold Belt in the Yukon Territory of Canada. The Company's Quebec exploration portfolio consists of three properties covering 8,148 hectares proximal to the town of Normétal and Amex Exploration's Perron project. The Company's Yukon exploration portfolio consists of several projects with a total land position of greater than 35,000 hectares, all of which are 100% owned by Generic Gold. Several of these projects are in close proximity to significant gold projects, including Goldcorp's Coffee project, Victoria Gold's Eagle Gold project, White Gold's Golden Saddle project, and Western Copper \u0026 Gold's Casino project. For information on the Company's property portfolio, visit the Company's website at genericgold.ca.For further information contact: Generic Gold Corp. Richard Patricio, President and CEO Tel: 416-456-6529 rpatricio@genericgold.caStephenAvenue Securities Inc.Daniel CappuccittiTel: 416-479-4478ecm@stephenavenue.comNEITHER THE CANADIAN SECURITIES EXCHANGE NOR THEIR REGULATION SERVICES PROVIDERS ACCEPT RESPONSIBILITY FOR THE ADEQUACY OR ACCURACY OF THIS RELEASE.Certain statements in this press release are \"forward-looking\" statements within the meaning of Canadian securities legislation. All statements, other than statements of historical fact, included herein are forward-looking information.  Forward-looking statements are necessarily based upon the current belief, opinions and expectations of management that, while considered reasonable by the Company, are inherently subject to business, economic, competitive, political and social uncertainties and other contingencies. Many factors could cause the Company's actual results to differ materially from those expressed or implied in the forward-looking statements. Accordingly, readers should not place undue reliance on forward-looking statements and forward-looking information. The Company does not undertake to update any forward-looking statements or forward-looking information that are incorporated by reference herein, except in accordance with applicable securities laws. Investors are cautioned not to put undue reliance on forward-looking statements due to the inherent uncertainty therein. We seek safe harbour.To view the source version of this press release, please visit https://www.newsfilecorp.com/release/60535GENERIC GOLD-Aktie komplett kostenlos handeln - auf Smartbroker.de"],"author":["Newsfile"],"datePublished":["2020-07-27T14:22"],"headline":["Generic Gold Announces Upsizing of Fully Subscribed Private Placement up to $7 Million"],"interactionCount":["UserPageVisits:305"],"keywords":["Generic Gold Announces Upsizing Fully Subscribed Private Placement Million"],"publisher":["https://www.finanznachrichten.de"],"url":["https://www.finanznachrichten.de/nachrichten-2020-07/50275112-generic-gold-announces-upsizing-of-fully-subscribed-private-placement-up-to-dollar-7-million-296.htm"]}}]}`
+	expected := `{"items":[{"type":["https://schema.org/WebPage"],"properties":{"breadcrumb":["Aktien»Nachrichten»GENERIC GOLD AKTIE»Generic Gold Announces Upsizing of Fully Subscribed Private Placement up to $7 Million"],"image":["https://www.finanznachrichten.de/chart-generic-gold-corp-aktie-1jahrklein-frankfurt.png"]}},{"type":["http://schema.org/Product"],"properties":{"image":["https://www.finanznachrichten.de/chart-generic-gold-corp-aktie-1jahrklein-frankfurt.png"],"name":["GENERIC GOLD CORP"],"offers":[{"type":["http://schema.org/Offer"],"properties":{"price":["0.28"],"priceCurrency":["EUR"]}}],"priceValidUntil":["2020-10-15T09:29:28.0000000"],"productID":["wkn:A2JAE9"],"seller":["Frankfurt"],"url":["https://www.finanznachrichten.de/nachrichten-aktien/generic-gold-corp.htm"]}},{"type":["http://schema.org/Article"],"properties":{"aggregateRating":[{"type":["http://schema.org/AggregateRating"],"properties":{"bestRating":["5"],"itemReviewed":["Generic Gold Announces Upsizing of Fully Subscribed Private Placement up to $7 Million"],"ratingCount":["2"],"ratingValue":["4,5"],"worstRating":["1"]}}],"articleBody":["Toronto, Ontario--(Newsfile Corp. - July 27, 2020) - Generic Gold Corp. (CSE: GGC) (FSE: 1WD) (\"Generic Gold\" or the \"Company\") is pleased to announce, further to its press release of July 16, 2020, the upsize of its fully subscribed \"best efforts\" private placement offering, led by StephenAvenue Securities Inc. (the \"Agent\") as sole agent and sole bookrunner (the \"Offering\"), for aggregate gross proceeds of up to $7,000,000, through the issuance of units (each, a \"Unit\") at a price of $0.35 per Unit and flow-through units (each, a \"FT Unit\") at a price of $0.40 per FT Unit (together, the Units and the FT Units, the \"Offered Securities\").The net proceeds from the sale of the Units will be used for general working capital and exploration purposes. The gross proceeds from the sale of the FT Units will be used by the Company to incur eligible \"Canadian exploration expenses\" that will qualify as \"flow-through mining expenditures\" (as such terms are defined in the Income Tax Act (Canada)) (the \"Qualifying Expenditures\") related to the Company's projects in Canada. All Qualifying Expenditures will be renounced in favour of the subscribers of the FT Units effective December 31, 2020. It is anticipated that most of the funds derived from the sale of the FT Units will be used to explore the Company's recently acquired Belvais project which is contiguous to Amex Exploration Inc.  (refer to the Company's press release of July 7, 2020).The Offering is expected to close on or about August 6, 2020 (the \"Closing Date\"), or such other date as agreed between the Company and the Agent. The completion of the Offering is subject to certain closing conditions including, but not limited to, the receipt of all necessary regulatory and other approvals including the approval of the Canadian Securities Exchange. All Offered Securities will be subject to a statutory hold period of four months and one day from the Closing Date.The Offered Securities have not been and will not be registered under the U.S. Securities Act of 1933, as amended, and may not be offered or sold in the United States absent registration or an applicable exemption from the registration requirements. This press release shall not constitute an offer to sell or the solicitation of an offer to buy nor shall there be any sale of the Offered Securities in any State in which such offer, solicitation or sale would be unlawful.About Generic GoldGeneric Gold is a Canadian mineral exploration company focused on gold projects in the Abitibi Greenstone Belt in Quebec, Canada and Tintina Gold Belt in the Yukon Territory of Canada. The Company's Quebec exploration portfolio consists of three properties covering 8,148 hectares proximal to the town of Normétal and Amex Exploration's Perron project. The Company's Yukon exploration portfolio consists of several projects with a total land position of greater than 35,000 hectares, all of which are 100% owned by Generic Gold. Several of these projects are in close proximity to significant gold projects, including Goldcorp's Coffee project, Victoria Gold's Eagle Gold project, White Gold's Golden Saddle project, and Western Copper \u0026 Gold's Casino project. For information on the Company's property portfolio, visit the Company's website at genericgold.ca.For further information contact: Generic Gold Corp. Richard Patricio, President and CEO Tel: 416-456-6529 rpatricio@genericgold.caStephenAvenue Securities Inc.Daniel CappuccittiTel: 416-479-4478ecm@stephenavenue.comNEITHER THE CANADIAN SECURITIES EXCHANGE NOR THEIR REGULATION SERVICES PROVIDERS ACCEPT RESPONSIBILITY FOR THE ADEQUACY OR ACCURACY OF THIS RELEASE.Certain statements in this press release are \"forward-looking\" statements within the meaning of Canadian securities legislation. All statements, other than statements of historical fact, included herein are forward-looking information.  Forward-looking statements are necessarily based upon the current belief, opinions and expectations of management that, while considered reasonable by the Company, are inherently subject to business, economic, competitive, political and social uncertainties and other contingencies. Many factors could cause the Company's actual results to differ materially from those expressed or implied in the forward-looking statements. Accordingly, readers should not place undue reliance on forward-looking statements and forward-looking information. The Company does not undertake to update any forward-looking statements or forward-looking information that are incorporated by reference herein, except in accordance with applicable securities laws. Investors are cautioned not to put undue reliance on forward-looking statements due to the inherent uncertainty therein. We seek safe harbour.To view the source version of this press release, please visit https://www.newsfilecorp.com/release/60535GENERIC GOLD-Aktie komplett kostenlos handeln - auf Smartbroker.de"],"author":["Newsfile"],"datePublished":["2020-07-27T14:22"],"headline":["Generic Gold Announces Upsizing of Fully Subscribed Private Placement up to $7 Million"],"interactionCount":["UserPageVisits:305"],"keywords":["Generic Gold Announces Upsizing Fully Subscribed Private Placement Million"],"publisher":["https://www.finanznachrichten.de"],"url":["https://www.finanznachrichten.de/nachrichten-2020-07/50275112-generic-gold-announces-upsizing-of-fully-subscribed-private-placement-up-to-dollar-7-million-296.htm"]}}]}`
 
 	if result != expected {
 		t.Errorf("Result should have been \"%s\", but it was \n \"%s\"", expected, result)
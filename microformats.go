@@ -0,0 +1,907 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Microformats holds the microformats2 of a document, in the canonical mf2
+// JSON shape.
+type Microformats struct {
+	Items   []*MicroformatItem  `json:"items"`
+	Rels    map[string][]string `json:"rels"`
+	RelURLs map[string]*RelURL  `json:"rel-urls"`
+	// Diagnostics lists the limits exceeded while parsing, see WithLimits.
+	Diagnostics []Diagnostic `json:"-"`
+}
+
+// MicroformatItem is a microformat, such as an h-card or an h-entry. Property
+// values are strings, *MicroformatItem values for nested microformats,
+// MicroformatImage values for images with alternative text and
+// MicroformatEmbed values for e-* properties.
+type MicroformatItem struct {
+	Type       []string                 `json:"type"`
+	Properties map[string][]interface{} `json:"properties"`
+	ID         string                   `json:"id,omitempty"`
+	// Value is the plain value of a microformat nested as property value.
+	Value    interface{}        `json:"value,omitempty"`
+	Children []*MicroformatItem `json:"children,omitempty"`
+
+	// kinds holds the kinds of the property values, by property.
+	kinds map[string][]ValueKind
+}
+
+// addProperty adds the property, value pair to the properties map. It appends
+// to any existing property.
+func (i *MicroformatItem) addProperty(property string, value interface{}, kind ValueKind) {
+	i.Properties[property] = append(i.Properties[property], value)
+	i.kinds[property] = append(i.kinds[property], kind)
+}
+
+// MicroformatImage is the value of a u-* property read from an img element
+// with alternative text.
+type MicroformatImage struct {
+	Value string `json:"value"`
+	Alt   string `json:"alt"`
+}
+
+// MicroformatEmbed is the value of an e-* property.
+type MicroformatEmbed struct {
+	HTML  string `json:"html"`
+	Value string `json:"value"`
+}
+
+// UnmarshalJSON decodes a microformat in the mf2 JSON shape, with property
+// values decoded as strings, *MicroformatItem, MicroformatImage or
+// MicroformatEmbed values, depending on their members.
+func (i *MicroformatItem) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Type       []string                     `json:"type"`
+		Properties map[string][]json.RawMessage `json:"properties"`
+		ID         string                       `json:"id"`
+		Value      interface{}                  `json:"value"`
+		Children   []*MicroformatItem           `json:"children"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	*i = MicroformatItem{
+		Type:       raw.Type,
+		Properties: make(map[string][]interface{}),
+		ID:         raw.ID,
+		Value:      raw.Value,
+		Children:   raw.Children,
+	}
+	for property, values := range raw.Properties {
+		for _, b := range values {
+			v, err := decodeMicroformatValue(b)
+			if err != nil {
+				return err
+			}
+			i.Properties[property] = append(i.Properties[property], v)
+		}
+	}
+	return nil
+}
+
+// decodeMicroformatValue decodes a property value in the mf2 JSON shape.
+// Objects with a type are microformats, objects with html embedded markup
+// and other objects with a value images.
+func decodeMicroformatValue(b []byte) (interface{}, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(b, &members); err != nil {
+		var v interface{}
+		err = json.Unmarshal(b, &v)
+		return v, err
+	}
+
+	var v interface{}
+	switch {
+	case members["type"] != nil:
+		v = &MicroformatItem{}
+	case members["html"] != nil:
+		v = &MicroformatEmbed{}
+	case members["value"] != nil:
+		v = &MicroformatImage{}
+	default:
+		var m map[string]interface{}
+		err := json.Unmarshal(b, &m)
+		return m, err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case *MicroformatEmbed:
+		return *v, nil
+	case *MicroformatImage:
+		return *v, nil
+	}
+	return v, nil
+}
+
+// RelURL describes a URL linked with a rel attribute.
+type RelURL struct {
+	Rels     []string `json:"rels"`
+	Text     string   `json:"text,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Media    string   `json:"media,omitempty"`
+	HrefLang string   `json:"hreflang,omitempty"`
+	Type     string   `json:"type,omitempty"`
+}
+
+var (
+	// mfRootPattern matches the class names of microformat roots.
+	mfRootPattern = regexp.MustCompile(`^h-(?:[a-z0-9]+-)?[a-z]+(?:-[a-z]+)*$`)
+	// mfPropertyPattern matches the class names of microformat properties.
+	mfPropertyPattern = regexp.MustCompile(`^(p|u|dt|e)-((?:[a-z0-9]+-)?[a-z]+(?:-[a-z]+)*)$`)
+)
+
+type mfParser struct {
+	tree    *html.Node
+	data    *Microformats
+	baseURL *url.URL
+	// limits caps the work done by the parser.
+	limits Limits
+	// itemCount is the number of microformats read.
+	itemCount int
+}
+
+// newMicroformatsParser returns a microformats2 parser for the given node
+// tree. A nil baseURL is treated as the empty URL.
+func newMicroformatsParser(tree *html.Node, baseURL *url.URL) *mfParser {
+	if baseURL == nil {
+		baseURL = &url.URL{}
+	}
+
+	return &mfParser{
+		tree: tree,
+		data: &Microformats{
+			Items:   []*MicroformatItem{},
+			Rels:    make(map[string][]string),
+			RelURLs: make(map[string]*RelURL),
+		},
+		baseURL: baseURL,
+	}
+}
+
+// parse returns the microformats and rel URLs of the parser's node tree.
+func (p *mfParser) parse() (*Microformats, error) {
+	p.baseURL = documentBaseURL(p.tree, p.baseURL)
+
+	p.findRoots(p.tree)
+
+	walkNodes(p.tree, func(n *html.Node) {
+		switch n.DataAtom {
+		case atom.A, atom.Area, atom.Link:
+			p.readRel(n)
+		}
+	})
+
+	return p.data, nil
+}
+
+// findRoots adds the top-level microformats among the given node and its
+// descendants.
+func (p *mfParser) findRoots(node *html.Node) {
+	stack := []*html.Node{node}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n.Type == html.ElementNode {
+			if types := rootClasses(n); len(types) > 0 {
+				if item := p.readItem(n, types); item != nil {
+					p.data.Items = append(p.data.Items, item)
+				}
+				continue
+			}
+		}
+		for c := n.LastChild; c != nil; c = c.PrevSibling {
+			stack = append(stack, c)
+		}
+	}
+}
+
+// mfItemState is a microformat being read by readItem.
+type mfItemState struct {
+	node *html.Node
+	item *MicroformatItem
+	// prefixes holds the prefixes of the properties found.
+	prefixes map[string]bool
+	// hasNested is set when nested microformats were found.
+	hasNested bool
+	// depth is the nesting depth of the microformat, starting at 1.
+	depth int
+	// values is the number of property values of the microformat.
+	values int
+}
+
+// mfFrame is an element to read for a microformat or, when done is set, a
+// function to call once the descendants of a nested microformat are read.
+type mfFrame struct {
+	node  *html.Node
+	state *mfItemState
+	done  func()
+}
+
+// readItem returns the microformat of the given root element, or nil when the
+// item limit is reached. Nested microformats are read using an explicit
+// stack, so deeply nested markup does not exhaust the goroutine stack.
+func (p *mfParser) readItem(root *html.Node, types []string) *MicroformatItem {
+	state := p.openItem(root, types, 1)
+	if state == nil {
+		return nil
+	}
+
+	stack := []mfFrame{{done: func() { p.closeItem(state) }}}
+	stack = pushChildren(stack, root, state)
+	for len(stack) > 0 {
+		f := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if f.done != nil {
+			f.done()
+			continue
+		}
+
+		c, parent := f.node, f.state
+		if c.Type != html.ElementNode {
+			continue
+		}
+
+		types := rootClasses(c)
+		properties := propertyClasses(c)
+
+		if len(types) > 0 {
+			parent.hasNested = true
+			if max := p.limits.MaxDepth; max > 0 && parent.depth >= max {
+				p.warn(DiagLimitExceeded, c, fmt.Sprintf("microformat nested deeper than %d levels; microformat skipped", max))
+				continue
+			}
+			nested := p.openItem(c, types, parent.depth+1)
+			if nested == nil {
+				continue
+			}
+			stack = append(stack, mfFrame{done: func() {
+				p.closeItem(nested)
+				p.addNested(parent, c, nested.item, properties)
+			}})
+			stack = pushChildren(stack, c, nested)
+			continue
+		}
+
+		for _, prop := range properties {
+			parent.prefixes[prop[0]] = true
+			switch prop[0] {
+			case "p":
+				p.addProperty(parent, c, prop[1], p.textValue(c), TextValue)
+			case "u":
+				if v, ok := p.urlValue(c); ok {
+					p.addProperty(parent, c, prop[1], v, URLValue)
+				}
+			case "dt":
+				p.addProperty(parent, c, prop[1], p.dateTimeValue(c), DateTimeValue)
+			case "e":
+				p.addProperty(parent, c, prop[1], MicroformatEmbed{HTML: innerHTML(c), Value: mfTextContent(c)}, TextValue)
+			}
+		}
+
+		stack = pushChildren(stack, c, parent)
+	}
+
+	return state.item
+}
+
+// pushChildren pushes the children of the given node, read for the given
+// microformat, so that they are popped in tree order.
+func pushChildren(stack []mfFrame, node *html.Node, state *mfItemState) []mfFrame {
+	for c := node.LastChild; c != nil; c = c.PrevSibling {
+		stack = append(stack, mfFrame{node: c, state: state})
+	}
+	return stack
+}
+
+// openItem starts reading the microformat of the given root element, nested
+// at the given depth. It returns nil when the item limit is reached.
+func (p *mfParser) openItem(node *html.Node, types []string, depth int) *mfItemState {
+	if max := p.limits.MaxItems; max > 0 && p.itemCount >= max {
+		if p.itemCount == max {
+			p.warn(DiagLimitExceeded, node, fmt.Sprintf("document has more than %d microformats; remaining microformats skipped", max))
+			p.itemCount++
+		}
+		return nil
+	}
+	p.itemCount++
+
+	item := &MicroformatItem{
+		Type:       types,
+		Properties: make(map[string][]interface{}),
+		kinds:      make(map[string][]ValueKind),
+	}
+	if id, ok := getAttr("id", node); ok {
+		item.ID = id
+	}
+	return &mfItemState{node: node, item: item, prefixes: make(map[string]bool), depth: depth}
+}
+
+// closeItem adds the implied properties of the microformat, once its
+// descendants are read, from the element itself or its only descendant.
+func (p *mfParser) closeItem(state *mfItemState) {
+	item, node, prefixes := state.item, state.node, state.prefixes
+	if _, ok := item.Properties["name"]; !ok && !prefixes["p"] && !prefixes["e"] && !state.hasNested {
+		p.addProperty(state, node, "name", p.impliedName(node), TextValue)
+	}
+	if _, ok := item.Properties["photo"]; !ok && !prefixes["u"] && !state.hasNested {
+		if photo := p.impliedPhoto(node); photo != nil {
+			p.addProperty(state, node, "photo", photo, URLValue)
+		}
+	}
+	if _, ok := item.Properties["url"]; !ok && !prefixes["u"] && !state.hasNested {
+		if u, ok := p.impliedURL(node); ok {
+			p.addProperty(state, node, "url", u, URLValue)
+		}
+	}
+}
+
+// addNested adds the nested microformat of the given element to the
+// microformat of its parent: as child, or as value of its properties.
+func (p *mfParser) addNested(parent *mfItemState, node *html.Node, nested *MicroformatItem, properties [][2]string) {
+	if len(properties) == 0 {
+		parent.item.Children = append(parent.item.Children, nested)
+		return
+	}
+	for _, prop := range properties {
+		parent.prefixes[prop[0]] = true
+		value := *nested
+		switch prop[0] {
+		case "u":
+			if u, ok := firstString(nested.Properties["url"]); ok {
+				value.Value = u
+			} else {
+				value.Value, _ = p.urlValue(node)
+			}
+		case "e":
+			value.Value = mfTextContent(node)
+		default:
+			if name, ok := firstString(nested.Properties["name"]); ok {
+				value.Value = name
+			} else {
+				value.Value = mfTextContent(node)
+			}
+		}
+		p.addProperty(parent, node, prop[1], &value, ItemValue)
+	}
+}
+
+// addProperty adds the property value, read from the given element, to the
+// microformat, up to the property value limit. Strings, and the plain text
+// of embedded markup, are truncated to the value length limit.
+func (p *mfParser) addProperty(state *mfItemState, node *html.Node, property string, value interface{}, kind ValueKind) {
+	if max := p.limits.MaxProperties; max > 0 && state.values >= max {
+		if state.values == max {
+			p.warn(DiagLimitExceeded, node, fmt.Sprintf("microformat has more than %d property values; remaining properties skipped", max))
+			state.values++
+		}
+		return
+	}
+	state.values++
+
+	switch v := value.(type) {
+	case string:
+		value = p.truncate(node, v)
+	case MicroformatEmbed:
+		v.Value = p.truncate(node, v.Value)
+		value = v
+	}
+	state.item.addProperty(property, value, kind)
+}
+
+// truncate truncates the given value, read from the given element, to the
+// value length limit.
+func (p *mfParser) truncate(node *html.Node, s string) string {
+	if max := p.limits.MaxValueLength; max > 0 && len(s) > max {
+		p.warn(DiagLimitExceeded, node, fmt.Sprintf("value longer than %d bytes; value truncated", max))
+		return truncate(s, max)
+	}
+	return s
+}
+
+// warn records a diagnostic for the given node.
+func (p *mfParser) warn(code string, node *html.Node, message string) {
+	d := Diagnostic{Code: code, Message: message, Element: node.Data, Node: node}
+	p.data.Diagnostics = append(p.data.Diagnostics, d)
+}
+
+// textValue returns the value of a p-* property element.
+func (p *mfParser) textValue(node *html.Node) string {
+	if v, ok := p.valueClassPattern(node); ok {
+		return v
+	}
+	switch node.DataAtom {
+	case atom.Abbr, atom.Link:
+		if title, ok := getAttr("title", node); ok {
+			return title
+		}
+	case atom.Data, atom.Input:
+		if value, ok := getAttr("value", node); ok {
+			return value
+		}
+	case atom.Img, atom.Area:
+		if alt, ok := getAttr("alt", node); ok {
+			return alt
+		}
+	}
+	return mfTextContent(node)
+}
+
+// urlValue returns the value of a u-* property element: either a resolved
+// URL string or, for images with alternative text, a MicroformatImage. It
+// reports false when the URL cannot be resolved.
+func (p *mfParser) urlValue(node *html.Node) (interface{}, bool) {
+	var attr string
+	switch node.DataAtom {
+	case atom.A, atom.Area, atom.Link:
+		attr = "href"
+	case atom.Img, atom.Audio, atom.Video, atom.Source, atom.Iframe:
+		attr = "src"
+	case atom.Object:
+		attr = "data"
+	}
+	if attr != "" {
+		if s, ok := getAttr(attr, node); ok {
+			u, ok := resolveURL(p.baseURL, strings.TrimSpace(s))
+			if alt, hasAlt := getAttr("alt", node); ok && hasAlt && node.DataAtom == atom.Img {
+				return MicroformatImage{Value: u, Alt: alt}, true
+			}
+			return u, ok
+		}
+	}
+	if node.DataAtom == atom.Video {
+		if s, ok := getAttr("poster", node); ok {
+			return resolveURL(p.baseURL, strings.TrimSpace(s))
+		}
+	}
+
+	if v, ok := p.valueClassPattern(node); ok {
+		return resolveURL(p.baseURL, v)
+	}
+	switch node.DataAtom {
+	case atom.Abbr:
+		if title, ok := getAttr("title", node); ok {
+			return resolveURL(p.baseURL, strings.TrimSpace(title))
+		}
+	case atom.Data, atom.Input:
+		if value, ok := getAttr("value", node); ok {
+			return resolveURL(p.baseURL, strings.TrimSpace(value))
+		}
+	}
+	return resolveURL(p.baseURL, mfTextContent(node))
+}
+
+// dateTimeValue returns the value of a dt-* property element.
+func (p *mfParser) dateTimeValue(node *html.Node) string {
+	if v, ok := p.valueClassPattern(node); ok {
+		return v
+	}
+	switch node.DataAtom {
+	case atom.Time, atom.Ins, atom.Del:
+		if datetime, ok := getAttr("datetime", node); ok {
+			return datetime
+		}
+	case atom.Abbr:
+		if title, ok := getAttr("title", node); ok {
+			return title
+		}
+	case atom.Data, atom.Input:
+		if value, ok := getAttr("value", node); ok {
+			return value
+		}
+	}
+	return mfTextContent(node)
+}
+
+// valueClassPattern returns the concatenated values of the descendants of
+// the given node with the class "value", or the title of descendants with the
+// class "value-title". It reports false when there are no such descendants.
+func (p *mfParser) valueClassPattern(node *html.Node) (string, bool) {
+	var parts []string
+	found := false
+
+	var stack []*html.Node
+	for c := node.LastChild; c != nil; c = c.PrevSibling {
+		stack = append(stack, c)
+	}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if c.Type != html.ElementNode || len(rootClasses(c)) > 0 {
+			continue
+		}
+		classes := classNames(c)
+		switch {
+		case classes["value-title"]:
+			found = true
+			title, _ := getAttr("title", c)
+			parts = append(parts, title)
+		case classes["value"]:
+			found = true
+			parts = append(parts, valueOf(c))
+		default:
+			for gc := c.LastChild; gc != nil; gc = gc.PrevSibling {
+				stack = append(stack, gc)
+			}
+		}
+	}
+
+	if !found {
+		return "", false
+	}
+	return strings.Join(parts, ""), true
+}
+
+// valueOf returns the value of an element with the class "value".
+func valueOf(node *html.Node) string {
+	switch node.DataAtom {
+	case atom.Img, atom.Area:
+		if alt, ok := getAttr("alt", node); ok {
+			return alt
+		}
+	case atom.Data:
+		if value, ok := getAttr("value", node); ok {
+			return value
+		}
+	case atom.Abbr:
+		if title, ok := getAttr("title", node); ok {
+			return title
+		}
+	case atom.Time, atom.Ins, atom.Del:
+		if datetime, ok := getAttr("datetime", node); ok {
+			return datetime
+		}
+	}
+	return mfTextContent(node)
+}
+
+// impliedName returns the implied name of the microformat of the given root
+// element.
+func (p *mfParser) impliedName(node *html.Node) string {
+	for _, n := range impliedCandidates(node) {
+		switch n.DataAtom {
+		case atom.Img, atom.Area:
+			if alt, ok := getAttr("alt", n); ok {
+				return alt
+			}
+		case atom.Abbr:
+			if title, ok := getAttr("title", n); ok {
+				return title
+			}
+		}
+	}
+	return mfTextContent(node)
+}
+
+// impliedPhoto returns the implied photo of the microformat of the given root
+// element, or nil when there is none.
+func (p *mfParser) impliedPhoto(node *html.Node) interface{} {
+	for _, n := range impliedCandidates(node) {
+		switch n.DataAtom {
+		case atom.Img:
+			if _, ok := getAttr("src", n); ok {
+				v, _ := p.urlValue(n)
+				return v
+			}
+		case atom.Object:
+			if _, ok := getAttr("data", n); ok {
+				v, _ := p.urlValue(n)
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// impliedURL returns the implied url of the microformat of the given root
+// element.
+func (p *mfParser) impliedURL(node *html.Node) (string, bool) {
+	for _, n := range impliedCandidates(node) {
+		switch n.DataAtom {
+		case atom.A, atom.Area:
+			if href, ok := getAttr("href", n); ok {
+				return resolveURL(p.baseURL, strings.TrimSpace(href))
+			}
+		}
+	}
+	return "", false
+}
+
+// impliedCandidates returns the elements implied properties are read from:
+// the root element, its only child element and that child's only child
+// element, as long as they are not microformats themselves.
+func impliedCandidates(node *html.Node) []*html.Node {
+	candidates := []*html.Node{node}
+	for n := node; len(candidates) < 3; {
+		child := onlyChildElement(n)
+		if child == nil || len(rootClasses(child)) > 0 {
+			break
+		}
+		candidates = append(candidates, child)
+		n = child
+	}
+	return candidates
+}
+
+// onlyChildElement returns the only child element of the given node, or nil.
+func onlyChildElement(node *html.Node) *html.Node {
+	var only *html.Node
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			if only != nil {
+				return nil
+			}
+			only = c
+		}
+	}
+	return only
+}
+
+// readRel records the rel attribute of a link element.
+func (p *mfParser) readRel(node *html.Node) {
+	rels := getAttrFields("rel", node)
+	href, ok := getAttr("href", node)
+	if len(rels) == 0 || !ok {
+		return
+	}
+	u, ok := resolveURL(p.baseURL, strings.TrimSpace(href))
+	if !ok {
+		return
+	}
+
+	relURL, ok := p.data.RelURLs[u]
+	if !ok {
+		relURL = &RelURL{}
+		relURL.Title, _ = getAttr("title", node)
+		relURL.Media, _ = getAttr("media", node)
+		relURL.HrefLang, _ = getAttr("hreflang", node)
+		relURL.Type, _ = getAttr("type", node)
+		if node.DataAtom == atom.A {
+			relURL.Text = mfTextContent(node)
+		}
+		p.data.RelURLs[u] = relURL
+	}
+
+	for _, rel := range rels {
+		if !containsString(relURL.Rels, rel) {
+			relURL.Rels = append(relURL.Rels, rel)
+		}
+		if !containsString(p.data.Rels[rel], u) {
+			p.data.Rels[rel] = append(p.data.Rels[rel], u)
+		}
+	}
+}
+
+// classNames returns the set of class names of the given element.
+func classNames(node *html.Node) map[string]bool {
+	classes := make(map[string]bool)
+	for _, class := range getAttrFields("class", node) {
+		classes[class] = true
+	}
+	return classes
+}
+
+// rootClasses returns the sorted microformat root class names of the given
+// element.
+func rootClasses(node *html.Node) []string {
+	var types []string
+	for class := range classNames(node) {
+		if mfRootPattern.MatchString(class) {
+			types = append(types, class)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// propertyClasses returns the microformat property class names of the given
+// element, split into prefix and property name.
+func propertyClasses(node *html.Node) [][2]string {
+	var properties [][2]string
+	seen := make(map[string]bool)
+	for _, class := range getAttrFields("class", node) {
+		if m := mfPropertyPattern.FindStringSubmatch(class); m != nil && !seen[class] {
+			seen[class] = true
+			properties = append(properties, [2]string{m[1], m[2]})
+		}
+	}
+	return properties
+}
+
+// mfTextContent returns the trimmed text content of the given node, leaving
+// out script and style elements and replacing images by their alternative
+// text.
+func mfTextContent(node *html.Node) string {
+	var buf bytes.Buffer
+
+	stack := []*html.Node{node}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case n.Type == html.TextNode:
+			buf.WriteString(n.Data)
+		case n.DataAtom == atom.Script || n.DataAtom == atom.Style:
+		case n.DataAtom == atom.Img:
+			if alt, ok := getAttr("alt", n); ok {
+				buf.WriteString(alt)
+			}
+		default:
+			for c := n.LastChild; c != nil; c = c.PrevSibling {
+				stack = append(stack, c)
+			}
+		}
+	}
+
+	return strings.TrimSpace(buf.String())
+}
+
+// innerHTML returns the serialized children of the given node.
+func innerHTML(node *html.Node) string {
+	var buf bytes.Buffer
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		html.Render(&buf, c)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// firstString returns the first string among the given values.
+func firstString(values []interface{}) (string, bool) {
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// containsString reports whether the slice contains the string.
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Microdata maps the microformats into the item model. Microformat root class
+// names, such as "h-card", become types and property names are used without
+// prefix. Nested microformats become nested items, and child microformats
+// are added as values of the "children" property. u-* values become URL
+// values and dt-* values datetime values; e-* values keep their plain text.
+func (m *Microformats) Microdata() *Microdata {
+	data := &Microdata{}
+	for _, mf := range m.Items {
+		data.addItem(mf.toItem())
+	}
+	return data
+}
+
+// toItem maps the microformat into the item model.
+func (i *MicroformatItem) toItem() *Item {
+	item := NewItem()
+	for _, t := range i.Type {
+		item.addType(t)
+	}
+	item.ID = i.ID
+
+	for property, values := range i.Properties {
+		for n, v := range values {
+			value := &Value{Kind: i.valueKind(property, n, v)}
+			switch v := v.(type) {
+			case string:
+				value.Raw = v
+			case MicroformatImage:
+				value.Raw = v.Value
+			case MicroformatEmbed:
+				value.Raw = v.Value
+			case *MicroformatItem:
+				value.Kind = ItemValue
+				value.Item = v.toItem()
+			}
+			item.addValue(property, value)
+		}
+	}
+
+	for _, child := range i.Children {
+		item.addValue("children", &Value{Kind: ItemValue, Item: child.toItem()})
+	}
+
+	return item
+}
+
+// valueKind returns the kind of the nth value of the given property. The
+// kinds are only known for microformats read by the parser; for others, such
+// as microformats decoded from JSON, the kind follows from the Go type of the
+// value, with plain strings as text.
+func (i *MicroformatItem) valueKind(property string, n int, v interface{}) ValueKind {
+	if kinds := i.kinds[property]; n < len(kinds) {
+		return kinds[n]
+	}
+	switch v.(type) {
+	case MicroformatImage:
+		return URLValue
+	case *MicroformatItem:
+		return ItemValue
+	}
+	return TextValue
+}
+
+// ParseMicroformatsTree returns the microformats2 of the given HTML document,
+// parsed with the mf2 parsing algorithm: p-, u-, dt- and e- properties,
+// implied name, photo and url properties, nested microformats, and rel and
+// rel-urls. Classic microformats are not parsed. The given url is used to
+// resolve the URLs, unless the document contains a base element. It is
+// equivalent to ParseMicroformatsTreeWith with WithBaseURL.
+func ParseMicroformatsTree(tree *html.Node, u *url.URL) (*Microformats, error) {
+	return ParseMicroformatsTreeWith(tree, WithBaseURL(u))
+}
+
+// ParseMicroformatsTreeWith returns the microformats2 of the given HTML
+// document. See ParseMicroformatsTree. WithBaseURL sets the URL used to
+// resolve the URLs, WithLimits caps the work done, and the handler of
+// WithDiagnostics is called with each diagnostic, without line and column.
+func ParseMicroformatsTreeWith(tree *html.Node, opts ...ParseOption) (*Microformats, error) {
+	cfg := newParseConfig(opts)
+	data, err := parseMicroformatsTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(nil, &Microdata{Diagnostics: data.Diagnostics})
+	return data, nil
+}
+
+// ParseMicroformats parses the HTML document available in the given reader and
+// returns its microformats2. See ParseMicroformatsTree and ParseHTML. It is
+// equivalent to ParseMicroformatsWith with WithContentType and WithBaseURL.
+func ParseMicroformats(r io.Reader, contentType string, u *url.URL) (*Microformats, error) {
+	return ParseMicroformatsWith(r, WithContentType(contentType), WithBaseURL(u))
+}
+
+// ParseMicroformatsWith parses the HTML document available in the given
+// reader and returns its microformats2. WithBaseURL and WithContentType apply
+// as with Parse, WithLimits caps the work done, and WithDiagnostics locates
+// the diagnostics in the source.
+func ParseMicroformatsWith(r io.Reader, opts ...ParseOption) (*Microformats, error) {
+	cfg := newParseConfig(opts)
+	tree, positions, err := parseSource(r, cfg.contentType, cfg.keepSource())
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseMicroformatsTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	// The diagnostics are shared, so they are located in place.
+	cfg.settleDiagnostics(positions, &Microdata{Diagnostics: data.Diagnostics})
+	return data, nil
+}
+
+// parseMicroformatsTree returns the microformats2 of the given node tree with
+// the given configuration, leaving the diagnostics to the caller.
+func parseMicroformatsTree(tree *html.Node, cfg *parseConfig) (*Microformats, error) {
+	p := newMicroformatsParser(tree, cfg.baseURL)
+	p.limits = cfg.limits
+	return p.parse()
+}
@@ -0,0 +1,206 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func parseMicroformatsJSON(html string, t *testing.T) string {
+	u, _ := url.Parse("http://example.com/blog/")
+	data, err := ParseMicroformats(strings.NewReader(html), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParseMicroformatsImplied(t *testing.T) {
+	html := `<a class="h-card" href="/ann"><img src="ann.jpg" alt="Ann Example"></a>`
+
+	result := parseMicroformatsJSON(html, t)
+	expected := `{"items":[{"type":["h-card"],"properties":{"name":["Ann Example"],"photo":[{"value":"http://example.com/blog/ann.jpg","alt":"Ann Example"}],"url":["http://example.com/ann"]}}],"rels":{},"rel-urls":{}}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseMicroformatsEntry(t *testing.T) {
+	html := `
+		<article class="h-entry" id="post-1">
+			<h1 class="p-name">Progress report</h1>
+			<a class="u-url" href="progress-report">Permalink</a>
+			<time class="dt-published" datetime="2013-08-29T15:00:00+02:00">August 29</time>
+			<div class="p-author h-card">
+				<a class="p-name u-url" href="/ann">Ann</a>
+			</div>
+			<div class="e-content"><p>All <b>good</b>.</p></div>
+			<span class="p-category">news</span>
+			<span class="p-category">progress</span>
+			<abbr class="dt-updated" title="2013-08-30"><span class="value">30</span> August</abbr>
+			<div class="h-cite"><span class="p-name">A reply</span></div>
+		</article>`
+
+	result := parseMicroformatsJSON(html, t)
+	expected := `{"items":[{"type":["h-entry"],"properties":{` +
+		`"author":[{"type":["h-card"],"properties":{"name":["Ann"],"url":["http://example.com/ann"]},"value":"Ann"}],` +
+		`"category":["news","progress"],` +
+		`"content":[{"html":"\u003cp\u003eAll \u003cb\u003egood\u003c/b\u003e.\u003c/p\u003e","value":"All good."}],` +
+		`"name":["Progress report"],` +
+		`"published":["2013-08-29T15:00:00+02:00"],` +
+		`"updated":["30"],` +
+		`"url":["http://example.com/blog/progress-report"]` +
+		`},"id":"post-1","children":[{"type":["h-cite"],"properties":{"name":["A reply"]}}]}],"rels":{},"rel-urls":{}}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseMicroformatsRels(t *testing.T) {
+	html := `
+		<link rel="me authn" href="https://github.com/ann" title="GitHub">
+		<a rel="me" href="/ann" hreflang="en">About Ann</a>
+		<a rel="me" href="https://github.com/ann">GitHub again</a>`
+
+	result := parseMicroformatsJSON(html, t)
+	expected := `{"items":[],` +
+		`"rels":{"authn":["https://github.com/ann"],"me":["https://github.com/ann","http://example.com/ann"]},` +
+		`"rel-urls":{"http://example.com/ann":{"rels":["me"],"text":"About Ann","hreflang":"en"},"https://github.com/ann":{"rels":["me","authn"],"title":"GitHub"}}}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestMicroformatsMicrodata(t *testing.T) {
+	html := `
+		<div class="h-event">
+			<span class="p-name">Concert</span>
+			<time class="dt-start" datetime="2015-07-19T20:00">Sunday</time>
+			<a class="u-url" href="/concert">Tickets</a>
+			<div class="p-location h-card"><span class="p-name">Park</span></div>
+		</div>`
+
+	data, err := ParseMicroformats(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := data.Microdata().Items[0]
+
+	if !item.HasType("h-event") {
+		t.Errorf("Result should have had type \"%s\", but it had \"%v\"", "h-event", item.Types)
+	}
+
+	var testTable = []struct {
+		path string
+		kind ValueKind
+		raw  string
+	}{
+		{"name", TextValue, "Concert"},
+		{"start", DateTimeValue, "2015-07-19T20:00"},
+		{"url", URLValue, "/concert"},
+		{"location.name", TextValue, "Park"},
+	}
+
+	for _, test := range testTable {
+		value := item.Get(test.path)
+		if value == nil {
+			t.Errorf("Result should have had a value for \"%s\"", test.path)
+			continue
+		}
+		if value.Kind != test.kind || value.Raw != test.raw {
+			t.Errorf("Result should have been %s \"%s\", but it was %s \"%s\"", test.kind, test.raw, value.Kind, value.Raw)
+		}
+	}
+}
+
+func TestMicroformatsMicrodataFromJSON(t *testing.T) {
+	html := `
+		<div class="h-entry">
+			<span class="p-name">Post</span>
+			<img class="u-photo" src="/photo.jpg" alt="Photo">
+			<div class="e-content"><p>Hello</p></div>
+			<div class="p-author h-card"><span class="p-name">Ann</span></div>
+		</div>`
+
+	data, err := ParseMicroformats(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Microformats
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := json.Marshal(decoded); string(result) != string(b) {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", b, result)
+	}
+	item := decoded.Microdata().Items[0]
+
+	var testTable = []struct {
+		path string
+		kind ValueKind
+		raw  string
+	}{
+		{"name", TextValue, "Post"},
+		{"photo", URLValue, "/photo.jpg"},
+		{"content", TextValue, "Hello"},
+		{"author.name", TextValue, "Ann"},
+	}
+
+	for _, test := range testTable {
+		value := item.Get(test.path)
+		if value == nil {
+			t.Errorf("Result should have had a value for \"%s\"", test.path)
+			continue
+		}
+		if value.Kind != test.kind || value.Raw != test.raw {
+			t.Errorf("Result should have been %s \"%s\", but it was %s \"%s\"", test.kind, test.raw, value.Kind, value.Raw)
+		}
+	}
+
+	// Microformats built by hand have no kinds either.
+	mf := &Microformats{Items: []*MicroformatItem{{
+		Type:       []string{"h-card"},
+		Properties: map[string][]interface{}{"name": {"Ann"}},
+	}}}
+	if name := mf.Microdata().Items[0].GetString("name"); name != "Ann" {
+		t.Errorf("Result should have been \"Ann\", but it was \"%s\"", name)
+	}
+}
+
+func TestParseMicroformatsWith(t *testing.T) {
+	html := "<a class=\"h-card\" href=\"/caf\xe9\">Caf\xe9 Example</a><div class=\"h-card\">Second</div>"
+
+	u, _ := url.Parse("http://example.com/blog/")
+	data, err := ParseMicroformatsWith(strings.NewReader(html), WithBaseURL(u),
+		WithContentType("text/html; charset=iso-8859-1"), WithLimits(Limits{MaxItems: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["h-card"],"properties":{"name":["Café Example"],"url":["http://example.com/caf%C3%A9"]}}],"rels":{},"rel-urls":{}}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+	if len(data.Diagnostics) != 1 {
+		t.Errorf("Result should have had 1 diagnostic, but it had %v", data.Diagnostics)
+	}
+}
@@ -0,0 +1,382 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The types of the items returned by ParseOpenGraph.
+const (
+	OpenGraphType   = "http://ogp.me/ns#"
+	TwitterCardType = "https://dev.twitter.com/cards"
+	DublinCoreType  = "http://purl.org/dc/terms/"
+)
+
+// openGraphPrefixes holds the prefixes of the OpenGraph properties, including
+// those of the object types.
+var openGraphPrefixes = map[string]bool{
+	"og":       true,
+	"fb":       true,
+	"article":  true,
+	"book":     true,
+	"books":    true,
+	"business": true,
+	"music":    true,
+	"place":    true,
+	"product":  true,
+	"profile":  true,
+	"video":    true,
+}
+
+// structuredProperties holds the array properties whose values carry
+// sub-properties, such as og:image and og:image:width.
+var structuredProperties = map[string]bool{
+	"og:image":       true,
+	"og:video":       true,
+	"og:audio":       true,
+	"music:song":     true,
+	"video:actor":    true,
+	"twitter:image":  true,
+	"twitter:player": true,
+}
+
+// urlProperties holds the properties whose values are URLs.
+var urlProperties = map[string]bool{
+	"og:url":         true,
+	"og:image":       true,
+	"og:video":       true,
+	"og:audio":       true,
+	"og:see_also":    true,
+	"music:song":     true,
+	"music:album":    true,
+	"music:musician": true,
+	"video:actor":    true,
+	"video:series":   true,
+	"twitter:image":  true,
+	"twitter:player": true,
+}
+
+type metaParser struct {
+	tree    *html.Node
+	data    *Microdata
+	baseURL *url.URL
+
+	openGraph, twitter, dublinCore *Item
+	// structured holds the last value of each structured property, to which
+	// sub-properties are added.
+	structured map[string]*Item
+	// urlSet holds the values of structured properties whose url was set by
+	// a url sub-property.
+	urlSet map[*Item]bool
+
+	// limits caps the work done by the parser.
+	limits Limits
+	// itemCount is the number of items read.
+	itemCount int
+	// admitted records whether each item is within the depth and item
+	// limits, and values holds the number of property values of the items.
+	admitted map[*Item]bool
+	values   map[*Item]int
+}
+
+// newMetaParser returns a meta tag parser for the given node tree. A nil
+// baseURL is treated as the empty URL.
+func newMetaParser(tree *html.Node, baseURL *url.URL) *metaParser {
+	if baseURL == nil {
+		baseURL = &url.URL{}
+	}
+
+	p := &metaParser{
+		tree:       tree,
+		data:       &Microdata{},
+		baseURL:    baseURL,
+		openGraph:  NewItem(),
+		twitter:    NewItem(),
+		dublinCore: NewItem(),
+		structured: make(map[string]*Item),
+		urlSet:     make(map[*Item]bool),
+		admitted:   make(map[*Item]bool),
+		values:     make(map[*Item]int),
+	}
+	p.openGraph.addType(OpenGraphType)
+	p.twitter.addType(TwitterCardType)
+	p.dublinCore.addType(DublinCoreType)
+	return p
+}
+
+// parse returns the OpenGraph, Twitter Card and Dublin Core items of the
+// parser's node tree. Vocabularies without meta tags are left out.
+func (p *metaParser) parse() (*Microdata, error) {
+	p.baseURL = documentBaseURL(p.tree, p.baseURL)
+
+	walkNodes(p.tree, func(n *html.Node) {
+		if n.DataAtom == atom.Meta {
+			p.readMeta(n)
+		}
+	})
+
+	for _, item := range []*Item{p.openGraph, p.twitter, p.dublinCore} {
+		if len(item.Properties) > 0 {
+			p.data.addItem(item)
+		}
+	}
+	return p.data, nil
+}
+
+// readMeta adds the property of the given meta element to the item of its
+// vocabulary. Both the property and the name attribute are accepted, as
+// publishers use either.
+func (p *metaParser) readMeta(node *html.Node) {
+	content, ok := getAttr("content", node)
+	if !ok {
+		return
+	}
+	key, ok := getAttr("property", node)
+	if !ok {
+		if key, ok = getAttr("name", node); !ok {
+			return
+		}
+	}
+	key = strings.TrimSpace(key)
+	lower := strings.ToLower(key)
+
+	switch {
+	case strings.HasPrefix(lower, "dc.") || strings.HasPrefix(lower, "dcterms."):
+		name := key[strings.IndexByte(key, '.')+1:]
+		p.addValue(p.dublinCore, node, name, p.newValue(content, dublinCoreKind(name)))
+	case strings.HasPrefix(lower, "twitter:"):
+		p.readProperty(p.twitter, node, lower, content)
+	default:
+		if i := strings.IndexByte(lower, ':'); i > 0 && openGraphPrefixes[lower[:i]] {
+			p.readProperty(p.openGraph, node, lower, content)
+		}
+	}
+}
+
+// readProperty adds an OpenGraph style property to the given item. Values of
+// structured properties become items holding a url property; their
+// sub-properties, such as og:image:width, are added to the last of them. The
+// url sub-property sets the url of the last value, and only starts a new value
+// when there is none or its url was already set by a url sub-property.
+func (p *metaParser) readProperty(item *Item, node *html.Node, key, content string) {
+	root, sub := key, ""
+	if !structuredProperties[key] {
+		if i := strings.LastIndexByte(key, ':'); i > 0 && structuredProperties[key[:i]] {
+			root, sub = key[:i], key[i+1:]
+		}
+	}
+
+	if !structuredProperties[root] {
+		p.addValue(item, node, key, p.newValue(content, metaKind(key)))
+		return
+	}
+
+	value, ok := p.structured[root]
+	if !ok || sub == "" || sub == "url" && p.urlSet[value] {
+		value = NewItem()
+		p.structured[root] = value
+		// The sub-properties of a skipped value are skipped too.
+		if p.admit(value, node, 2) {
+			p.addValue(item, node, root, &Value{Kind: ItemValue, Item: value, Element: "meta"})
+		}
+	}
+
+	// The url sub-property is the same as the property itself.
+	switch sub {
+	case "":
+		p.addValue(value, node, "url", p.newValue(content, metaKind(root)))
+	case "url":
+		p.values[value] -= len(value.Properties["url"])
+		delete(value.Properties, "url")
+		p.addValue(value, node, "url", p.newValue(content, metaKind(root)))
+		p.urlSet[value] = true
+	default:
+		p.addValue(value, node, sub, p.newValue(content, metaKind(key)))
+	}
+}
+
+// admit reports whether the given item, nested at the given depth, is within
+// the depth and item limits. The first call for an item decides.
+func (p *metaParser) admit(item *Item, node *html.Node, depth int) bool {
+	if ok, seen := p.admitted[item]; seen {
+		return ok
+	}
+
+	ok := true
+	if max := p.limits.MaxDepth; max > 0 && depth > max {
+		p.warn(node, fmt.Sprintf("item nested deeper than %d levels; item skipped", max))
+		ok = false
+	} else if max := p.limits.MaxItems; max > 0 && p.itemCount >= max {
+		if p.itemCount == max {
+			p.warn(node, fmt.Sprintf("document has more than %d items; remaining items skipped", max))
+			p.itemCount++
+		}
+		ok = false
+	} else {
+		p.itemCount++
+	}
+	p.admitted[item] = ok
+	return ok
+}
+
+// addValue adds the value, read from the given meta element, to the given
+// item as the property name, within the limits.
+func (p *metaParser) addValue(item *Item, node *html.Node, name string, value *Value) {
+	// The vocabulary items are top-level items; structured values are
+	// admitted when they are created.
+	if !p.admit(item, node, 1) {
+		return
+	}
+	if max := p.limits.MaxProperties; max > 0 && p.values[item] >= max {
+		if p.values[item] == max {
+			p.warn(node, fmt.Sprintf("item has more than %d property values; remaining properties skipped", max))
+			p.values[item]++
+		}
+		return
+	}
+	if max := p.limits.MaxValueLength; max > 0 && len(value.Raw) > max {
+		p.warn(node, fmt.Sprintf("value longer than %d bytes; value truncated", max))
+		value.Raw = truncate(value.Raw, max)
+	}
+	item.addValue(name, value)
+	p.values[item]++
+}
+
+// warn records a diagnostic for the given node.
+func (p *metaParser) warn(node *html.Node, message string) {
+	p.data.addDiagnostic(Diagnostic{
+		Code:    DiagLimitExceeded,
+		Message: message,
+		Node:    node,
+	})
+}
+
+// newValue returns a value of the given kind. URLs are resolved against the
+// base URL, and kept as text when they cannot be resolved, as are numbers
+// that are not numeric.
+func (p *metaParser) newValue(content string, kind ValueKind) *Value {
+	value := &Value{Kind: kind, Raw: content, Element: "meta"}
+	switch kind {
+	case URLValue:
+		if s, ok := resolveURL(p.baseURL, strings.TrimSpace(content)); ok {
+			value.Raw = s
+		} else {
+			value.Kind = TextValue
+		}
+	case NumberValue:
+		if !isNumber(content) {
+			value.Kind = TextValue
+		}
+	}
+	return value
+}
+
+// metaKind returns the kind of the values of the given OpenGraph or Twitter
+// Card property.
+func metaKind(key string) ValueKind {
+	switch {
+	case urlProperties[key],
+		strings.HasSuffix(key, ":url"),
+		strings.HasSuffix(key, ":secure_url"),
+		strings.HasSuffix(key, ":src"),
+		strings.HasSuffix(key, ":stream"):
+		return URLValue
+	case strings.HasSuffix(key, "_time"), strings.HasSuffix(key, "_date"):
+		return DateTimeValue
+	case strings.HasSuffix(key, ":width"),
+		strings.HasSuffix(key, ":height"),
+		strings.HasSuffix(key, ":duration"),
+		strings.HasSuffix(key, ":disc"),
+		strings.HasSuffix(key, ":track"):
+		return NumberValue
+	}
+	return TextValue
+}
+
+// dublinCoreKind returns the kind of the values of the given Dublin Core
+// element, such as "date" or "date.issued".
+func dublinCoreKind(name string) ValueKind {
+	name = strings.ToLower(name)
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+	switch name {
+	case "date", "created", "modified", "issued", "available", "valid":
+		return DateTimeValue
+	}
+	return TextValue
+}
+
+// ParseOpenGraphTree returns the items described by the OpenGraph, Twitter
+// Card and Dublin Core meta tags of the given HTML document, one item per
+// vocabulary, with the types OpenGraphType, TwitterCardType and DublinCoreType.
+//
+// OpenGraph and Twitter Card property names are lowercased and keep their
+// prefix, e.g. "og:title" and "article:published_time". Structured properties,
+// such as og:image, become nested items with a url property and the
+// sub-properties that follow, e.g. "og:image.width". Dublin Core property
+// names, written as DC.* or DCTERMS.*, are the element names, e.g. "creator".
+// The given url is used to resolve the URLs, unless the document contains a
+// base element. It is equivalent to ParseOpenGraphTreeWith with WithBaseURL.
+func ParseOpenGraphTree(tree *html.Node, u *url.URL) (*Microdata, error) {
+	return ParseOpenGraphTreeWith(tree, WithBaseURL(u))
+}
+
+// ParseOpenGraphTreeWith returns the items described by the OpenGraph, Twitter
+// Card and Dublin Core meta tags of the given HTML document. See
+// ParseOpenGraphTree. WithBaseURL sets the URL used to resolve the URLs,
+// WithLimits caps the work done, structured values being nested items, and
+// the handler of WithDiagnostics is called with each diagnostic, without line
+// and column.
+func ParseOpenGraphTreeWith(tree *html.Node, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+	data, err := parseOpenGraphTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(nil, data)
+	return data, nil
+}
+
+// ParseOpenGraph parses the HTML document available in the given reader and
+// returns the items described by its OpenGraph, Twitter Card and Dublin Core
+// meta tags. See ParseOpenGraphTree and ParseHTML. It is equivalent to
+// ParseOpenGraphWith with WithContentType and WithBaseURL.
+func ParseOpenGraph(r io.Reader, contentType string, u *url.URL) (*Microdata, error) {
+	return ParseOpenGraphWith(r, WithContentType(contentType), WithBaseURL(u))
+}
+
+// ParseOpenGraphWith parses the HTML document available in the given reader
+// and returns the items described by its OpenGraph, Twitter Card and Dublin
+// Core meta tags. WithBaseURL and WithContentType apply as with Parse,
+// WithLimits caps the work done, and WithDiagnostics locates the diagnostics
+// in the source.
+func ParseOpenGraphWith(r io.Reader, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+	tree, positions, err := parseSource(r, cfg.contentType, cfg.keepSource())
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseOpenGraphTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(positions, data)
+	return data, nil
+}
+
+// parseOpenGraphTree returns the meta tag items of the given node tree with
+// the given configuration, leaving the diagnostics to the caller.
+func parseOpenGraphTree(tree *html.Node, cfg *parseConfig) (*Microdata, error) {
+	p := newMetaParser(tree, cfg.baseURL)
+	p.limits = cfg.limits
+	return p.parse()
+}
@@ -0,0 +1,213 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var openGraphSnippet = `
+<html>
+	<head>
+		<meta property="og:title" content="The Rock">
+		<meta property="og:type" content="video.movie">
+		<meta property="og:url" content="/title/tt0117500/">
+		<meta property="og:image" content="rock.jpg">
+		<meta property="og:image:width" content="400">
+		<meta property="og:image:height" content="300">
+		<meta property="og:image:url" content="rock2.jpg">
+		<meta property="og:image:alt" content="A shiny red apple">
+		<meta property="article:published_time" content="2015-07-19T20:00:00Z">
+		<meta name="twitter:card" content="summary_large_image">
+		<meta name="twitter:image" content="https://example.com/card.jpg">
+		<meta name="twitter:image:alt" content="Card">
+		<meta name="DC.creator" content="Michael Bay">
+		<meta name="DCTERMS.date.issued" content="1996-06-07">
+		<meta name="description" content="Not part of a vocabulary">
+	</head>
+</html>`
+
+func TestParseOpenGraph(t *testing.T) {
+	u, _ := url.Parse("http://www.imdb.com/")
+	data, err := ParseOpenGraph(strings.NewReader(openGraphSnippet), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[` +
+		`{"type":["http://ogp.me/ns#"],"properties":{` +
+		`"article:published_time":["2015-07-19T20:00:00Z"],` +
+		`"og:image":[` +
+		`{"type":[],"properties":{"alt":["A shiny red apple"],"height":["300"],"url":["http://www.imdb.com/rock2.jpg"],"width":["400"]}}],` +
+		`"og:title":["The Rock"],` +
+		`"og:type":["video.movie"],` +
+		`"og:url":["http://www.imdb.com/title/tt0117500/"]}},` +
+		`{"type":["https://dev.twitter.com/cards"],"properties":{` +
+		`"twitter:card":["summary_large_image"],` +
+		`"twitter:image":[{"type":[],"properties":{"alt":["Card"],"url":["https://example.com/card.jpg"]}}]}},` +
+		`{"type":["http://purl.org/dc/terms/"],"properties":{` +
+		`"creator":["Michael Bay"],` +
+		`"date.issued":["1996-06-07"]}}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseOpenGraphImageURL(t *testing.T) {
+	html := `
+		<meta property="og:image:url" content="http://example.com/a.jpg">
+		<meta property="og:image:width" content="400">
+		<meta property="og:image:url" content="http://example.com/b.jpg">
+		<meta property="og:image" content="http://example.com/c.jpg">
+		<meta property="og:image:url" content="http://example.com/c.jpg">
+		<meta property="og:image:alt" content="C">`
+
+	data, err := ParseOpenGraph(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data.Items[0].Properties["og:image"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `[` +
+		`{"type":[],"properties":{"url":["http://example.com/a.jpg"],"width":["400"]}},` +
+		`{"type":[],"properties":{"url":["http://example.com/b.jpg"]}},` +
+		`{"type":[],"properties":{"alt":["C"],"url":["http://example.com/c.jpg"]}}]`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseOpenGraphValueKind(t *testing.T) {
+	data, err := ParseOpenGraph(strings.NewReader(openGraphSnippet), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		typ  string
+		path string
+		kind ValueKind
+	}{
+		{OpenGraphType, "og:title", TextValue},
+		{OpenGraphType, "og:url", URLValue},
+		{OpenGraphType, "og:image", ItemValue},
+		{OpenGraphType, "og:image.url", URLValue},
+		{OpenGraphType, "og:image.width", NumberValue},
+		{OpenGraphType, "article:published_time", DateTimeValue},
+		{TwitterCardType, "twitter:image.url", URLValue},
+		{DublinCoreType, "date.issued", DateTimeValue},
+	}
+
+	for _, test := range testTable {
+		items := data.ItemsOfType(test.typ)
+		if len(items) != 1 {
+			t.Errorf("Result should have had one item of type \"%s\", but it had %d", test.typ, len(items))
+			continue
+		}
+		value := items[0].Get(test.path)
+		if value == nil {
+			t.Errorf("Result should have had a value for \"%s\"", test.path)
+			continue
+		}
+		if value.Kind != test.kind {
+			t.Errorf("Result should have been %s for \"%s\", but it was %s", test.kind, test.path, value.Kind)
+		}
+	}
+}
+
+func TestParseOpenGraphEmpty(t *testing.T) {
+	data, err := ParseOpenGraph(strings.NewReader(`<meta name="description" content="Nothing">`), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) != 0 {
+		t.Errorf("Result should have had no items, but it had %d", len(data.Items))
+	}
+}
+
+func TestParseOpenGraphLimits(t *testing.T) {
+	html := `
+		<meta property="og:title" content="The Rock">
+		<meta property="og:image" content="http://example.com/rock.jpg">
+		<meta property="og:image:width" content="400">
+		<meta property="og:image:height" content="300">
+		<meta property="og:image" content="http://example.com/rock2.jpg">
+		<meta property="og:image:width" content="800">
+		<meta property="og:description" content="Not kept">`
+
+	var reported []Diagnostic
+	data, err := ParseOpenGraphWith(strings.NewReader(html),
+		WithLimits(Limits{MaxItems: 2, MaxProperties: 2, MaxValueLength: 6}),
+		WithDiagnostics(func(d Diagnostic) { reported = append(reported, d) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["http://ogp.me/ns#"],"properties":{` +
+		`"og:image":[{"type":[],"properties":{"url":["http:/"],"width":["400"]}}],` +
+		`"og:title":["The Ro"]}}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	// Two truncated values, a skipped image property, a skipped image and
+	// a skipped vocabulary property.
+	var lines []int
+	for _, d := range reported {
+		if d.Code != DiagLimitExceeded {
+			t.Errorf("Result should have been a limit diagnostic, but it was %v", d)
+		}
+		lines = append(lines, d.Line)
+	}
+	if expected := []int{2, 3, 5, 6, 8}; !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Result should have been reported at lines %v, but it was at %v", expected, lines)
+	}
+}
+
+func TestParseOpenGraphNotNumber(t *testing.T) {
+	html := `
+		<meta property="og:image" content="http://example.com/rock.jpg">
+		<meta property="og:image:width" content="400px">
+		<meta property="og:image:height" content=" 300 ">
+		<meta property="video:duration" content="about an hour">`
+
+	data, err := ParseOpenGraph(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		path string
+		kind ValueKind
+	}{
+		{"og:image.width", TextValue},
+		{"og:image.height", NumberValue},
+		{"video:duration", TextValue},
+	}
+
+	item := data.Items[0]
+	for _, test := range testTable {
+		if result := item.Get(test.path).Kind; result != test.kind {
+			t.Errorf("Result should have been %s for \"%s\", but it was %s", test.kind, test.path, result)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Fetcher fetches documents over HTTP. *http.Client implements Fetcher.
+type Fetcher interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ParseOption configures how a document is fetched and parsed.
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	fetcher     Fetcher
+	syntaxes    Syntax
+	baseURL     *url.URL
+	contentType string
+	positions   bool
+	limits      Limits
+	diagnostics func(Diagnostic)
+	// locate is set when the diagnostics are to be located in the source.
+	locate bool
+	strict bool
+}
+
+// keepSource reports whether the source of the document is to be kept after
+// parsing, to record positions or locate diagnostics. Otherwise the document
+// is parsed as it is read.
+func (cfg *parseConfig) keepSource() bool {
+	return cfg.positions || cfg.locate
+}
+
+// newParseConfig returns the configuration resulting from applying the given
+// options to the defaults.
+func newParseConfig(opts []ParseOption) *parseConfig {
+	cfg := &parseConfig{
+		fetcher:  http.DefaultClient,
+		syntaxes: AllSyntaxes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithHTTPClient sets the fetcher used to retrieve documents. The default is
+// http.DefaultClient.
+func WithHTTPClient(f Fetcher) ParseOption {
+	return func(cfg *parseConfig) {
+		if f != nil {
+			cfg.fetcher = f
+		}
+	}
+}
+
+// WithSyntaxes sets the syntaxes read by Extract. The default is AllSyntaxes.
+func WithSyntaxes(s Syntax) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.syntaxes = s
+	}
+}
+
+// WithBaseURL sets the URL used to resolve relative URLs, unless the document
+// contains a base element. A nil URL is treated as the empty URL.
+func WithBaseURL(u *url.URL) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.baseURL = u
+	}
+}
+
+// WithContentType sets the content type used to convert the document to
+// UTF-8. By default the content type is detected using
+// http.DetectContentType.
+func WithContentType(contentType string) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.contentType = contentType
+	}
+}
+
+// WithPositions records the position in the source of the element of each
+// microdata item and property value, in Item.Position and Value.Position, and
+// the line and column of the diagnostics. Positions require the source, which
+// is kept in memory while parsing, so they are not recorded by ExtractTree.
+// Elements the HTML parser implies, such as tbody, have no position, and the
+// copies it makes of misnested formatting elements, such as a and b, share
+// the position of the original. An element closed implicitly ends where the
+// element or end tag closing it starts.
+func WithPositions() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.positions = true
+	}
+}
+
+// Limits caps the work done parsing a document, e.g. to protect a crawler
+// from adversarial markup. Content exceeding a limit is skipped or truncated,
+// with a DiagLimitExceeded diagnostic. Zero values mean no limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of items; top-level items are at
+	// depth 1. Deeper items are skipped.
+	MaxDepth int
+	// MaxItems is the maximum number of items, nested items included.
+	MaxItems int
+	// MaxProperties is the maximum number of property values of an item.
+	MaxProperties int
+	// MaxValueLength is the maximum length of a value in bytes. Longer
+	// values are truncated.
+	MaxValueLength int
+}
+
+// WithLimits sets the limits of the parsers of all syntaxes. Structured
+// OpenGraph values, such as og:image, count as nested items. By default there
+// are no limits.
+func WithLimits(l Limits) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.limits = l
+	}
+}
+
+// WithDiagnostics sets a handler called with each diagnostic found while
+// parsing, with its line and column when the source is available. The
+// diagnostics are reported in Microdata.Diagnostics as well, located in the
+// source too. Locating them requires the source, which is kept in memory
+// while parsing. The handler may be nil, to only locate the diagnostics.
+func WithDiagnostics(handler func(Diagnostic)) ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.diagnostics = handler
+		cfg.locate = true
+	}
+}
+
+// WithStrictMode makes the microdata parser follow the algorithm of the WHATWG
+// HTML specification exactly, instead of the lenient defaults:
+//
+//   - itemprop, itemtype and itemref are split on ASCII whitespace, not only
+//     spaces, and duplicate tokens are dropped;
+//   - itemtype tokens that are not absolute URLs are dropped, and types of
+//     different vocabularies are reported;
+//   - property names containing "." or ":" must be absolute URLs;
+//   - the content attribute is only read on meta elements;
+//   - object elements are URL properties, read from the data attribute;
+//   - time elements without datetime attribute have their text as value;
+//   - URLs are trimmed of surrounding whitespace;
+//   - properties with an empty value are kept.
+//
+// Deviations from the algorithm are reported as diagnostics.
+func WithStrictMode() ParseOption {
+	return func(cfg *parseConfig) {
+		cfg.strict = true
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Position locates the element an item or a value was read from in the
+// source of the document. Offsets are byte offsets in the source converted to
+// UTF-8, which is the source itself for UTF-8 documents.
+type Position struct {
+	// Start is the offset of the start tag of the element, End the offset
+	// following its end tag, or its start tag when it has no end tag.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Line and Column locate Start, EndLine and EndColumn locate End. They
+	// start at 1, with the column counted in characters.
+	Line      int `json:"line"`
+	Column    int `json:"column"`
+	EndLine   int `json:"endLine"`
+	EndColumn int `json:"endColumn"`
+}
+
+// closesParagraph holds the elements whose start tag closes an open p
+// element.
+var closesParagraph = map[atom.Atom]bool{
+	atom.Address:    true,
+	atom.Article:    true,
+	atom.Aside:      true,
+	atom.Blockquote: true,
+	atom.Details:    true,
+	atom.Dialog:     true,
+	atom.Div:        true,
+	atom.Dl:         true,
+	atom.Fieldset:   true,
+	atom.Figcaption: true,
+	atom.Figure:     true,
+	atom.Footer:     true,
+	atom.Form:       true,
+	atom.H1:         true,
+	atom.H2:         true,
+	atom.H3:         true,
+	atom.H4:         true,
+	atom.H5:         true,
+	atom.H6:         true,
+	atom.Header:     true,
+	atom.Hgroup:     true,
+	atom.Hr:         true,
+	atom.Main:       true,
+	atom.Menu:       true,
+	atom.Nav:        true,
+	atom.Ol:         true,
+	atom.P:          true,
+	atom.Pre:        true,
+	atom.Section:    true,
+	atom.Table:      true,
+	atom.Ul:         true,
+}
+
+// paragraphScope holds the elements an open p element is not closed across.
+var paragraphScope = map[atom.Atom]bool{
+	atom.Applet:   true,
+	atom.Button:   true,
+	atom.Caption:  true,
+	atom.Html:     true,
+	atom.Marquee:  true,
+	atom.Object:   true,
+	atom.Table:    true,
+	atom.Td:       true,
+	atom.Template: true,
+	atom.Th:       true,
+}
+
+// impliedEnd returns the index, in the stack of n open elements, of the
+// element closed by the start tag of the given element, e.g. the previous li
+// for a li, or -1. The open elements above it are closed with it. The stack
+// is only searched when counts, the number of open elements by name, has
+// an element to close. It covers the common cases of the tree construction
+// algorithm of the HTML parser, for the stream parser.
+func impliedEnd(n int, open func(i int) atom.Atom, counts map[atom.Atom]int, a atom.Atom) int {
+	var closes, stops []atom.Atom
+	switch a {
+	case atom.Li:
+		closes, stops = []atom.Atom{atom.Li}, []atom.Atom{atom.Ol, atom.Ul}
+	case atom.Dd, atom.Dt:
+		closes, stops = []atom.Atom{atom.Dd, atom.Dt}, []atom.Atom{atom.Dl}
+	case atom.Td, atom.Th:
+		closes, stops = []atom.Atom{atom.Td, atom.Th}, []atom.Atom{atom.Tr, atom.Table}
+	case atom.Tr:
+		closes, stops = []atom.Atom{atom.Tr}, []atom.Atom{atom.Table, atom.Tbody, atom.Thead, atom.Tfoot}
+	case atom.Option:
+		closes, stops = []atom.Atom{atom.Option}, []atom.Atom{atom.Select, atom.Datalist}
+	default:
+		if !closesParagraph[a] {
+			return -1
+		}
+		closes = []atom.Atom{atom.P}
+	}
+
+	found := false
+	for _, c := range closes {
+		found = found || counts[c] > 0
+	}
+	if !found {
+		return -1
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		current := open(i)
+		for _, c := range closes {
+			if current == c {
+				return i
+			}
+		}
+		for _, s := range stops {
+			if current == s {
+				return -1
+			}
+		}
+		if stops == nil && paragraphScope[current] {
+			return -1
+		}
+	}
+	return -1
+}
+
+// voidElements holds the elements that have no end tag.
+var voidElements = map[atom.Atom]bool{
+	atom.Area:   true,
+	atom.Base:   true,
+	atom.Br:     true,
+	atom.Col:    true,
+	atom.Embed:  true,
+	atom.Hr:     true,
+	atom.Img:    true,
+	atom.Input:  true,
+	atom.Link:   true,
+	atom.Meta:   true,
+	atom.Param:  true,
+	atom.Source: true,
+	atom.Track:  true,
+	atom.Wbr:    true,
+}
+
+// positionAttr is the attribute marking each start tag of the source with its
+// index, for parsePositions.
+const positionAttr = "microdata-start-tag"
+
+// startTag is a start tag of the source, located by parsePositions.
+type startTag struct {
+	start, end int
+	// closed reports whether the element ends with its start tag: void
+	// elements and self-closing foreign elements.
+	closed bool
+}
+
+// parsePositions parses the given source into a node tree and returns it with
+// the positions of its elements. The HTML parser does not record positions,
+// so the source is tokenized first and each start tag is given an attribute
+// holding its index, which the parser copies to the element it creates from
+// the tag; the attribute is removed from the tree afterwards. Elements implied
+// by the parser, such as tbody, have no position, and elements it clones when
+// recovering from misnested formatting elements share the position of the
+// original. As the attribute differs between start tags, the parser no longer
+// limits the number of identical open formatting elements it reopens to three.
+//
+// The end of an element is found by walking the tokens again with the open
+// elements of the tree: a start tag closes the open elements that are not
+// ancestors of its element, at the offset of the tag, and an end tag closes
+// the innermost open element of the same name.
+func parsePositions(src []byte) (*html.Node, map[*html.Node]Position, error) {
+	type endTag struct {
+		name       string
+		start, end int
+	}
+	// tokens holds the start tags, as indices in tags, and the end tags, as
+	// indices in ends offset by len(src), in source order.
+	var tags []startTag
+	var ends []endTag
+	var tokens []int
+
+	var marked bytes.Buffer
+	marked.Grow(len(src) + len(src)/8)
+	z := html.NewTokenizer(bytes.NewReader(src))
+	offset, copied, foreign := 0, 0, 0
+	for {
+		// The parser recognizes CDATA sections in foreign content only; they
+		// are tokenized as such within svg and math elements, so that no
+		// attribute is added to what the parser reads as text.
+		z.AllowCDATA(foreign > 0)
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		end := offset + len(z.Raw())
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			if tt == html.StartTagToken && (a == atom.Svg || a == atom.Math) {
+				foreign++
+			}
+
+			// The attribute follows the tag name, and its value is quoted,
+			// so that it does not change the other attributes.
+			at := offset + 1 + len(name)
+			marked.Write(src[copied:at])
+			fmt.Fprintf(&marked, " %s=\"%d\"", positionAttr, len(tags))
+			copied = at
+
+			tokens = append(tokens, len(tags))
+			tags = append(tags, startTag{start: offset, end: end, closed: voidElements[a] || tt == html.SelfClosingTagToken})
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if a := atom.Lookup(name); (a == atom.Svg || a == atom.Math) && foreign > 0 {
+				foreign--
+			}
+			tokens = append(tokens, len(src)+len(ends))
+			ends = append(ends, endTag{name: string(name), start: offset, end: end})
+		}
+		offset = end
+	}
+	marked.Write(src[copied:])
+
+	tree, err := html.Parse(&marked)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// elements holds the elements created from each start tag, the original
+	// first.
+	elements := make([][]*html.Node, len(tags))
+	walkNodes(tree, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		attrs := n.Attr[:0]
+		for _, attr := range n.Attr {
+			if attr.Key != positionAttr || attr.Namespace != "" {
+				attrs = append(attrs, attr)
+				continue
+			}
+			if i, err := strconv.Atoi(attr.Val); err == nil && i >= 0 && i < len(tags) {
+				elements[i] = append(elements[i], n)
+			}
+		}
+		n.Attr = attrs
+	})
+
+	var open []*html.Node
+	isOpen := make(map[*html.Node]bool)
+	ending := make(map[*html.Node]int)
+	// closeTo closes the open elements above index i of the open stack at the
+	// given offset.
+	closeTo := func(i, offset int) {
+		for _, n := range open[i:] {
+			ending[n] = offset
+			delete(isOpen, n)
+		}
+		open = open[:i]
+	}
+	for _, t := range tokens {
+		if t >= len(src) {
+			e := ends[t-len(src)]
+			for i := len(open) - 1; i >= 0; i-- {
+				if strings.EqualFold(open[i].Data, e.name) {
+					closeTo(i+1, e.start)
+					closeTo(i, e.end)
+					break
+				}
+			}
+			continue
+		}
+
+		if len(elements[t]) == 0 {
+			// The parser dropped the tag, e.g. a nested form.
+			continue
+		}
+		n, tag := elements[t][0], tags[t]
+
+		ancestor := n.Parent
+		for ancestor != nil && !isOpen[ancestor] {
+			ancestor = ancestor.Parent
+		}
+		i := len(open)
+		for i > 0 && open[i-1] != ancestor {
+			i--
+		}
+		closeTo(i, tag.start)
+
+		if tag.closed {
+			ending[n] = tag.end
+		} else {
+			open = append(open, n)
+			isOpen[n] = true
+		}
+	}
+	closeTo(0, len(src))
+
+	lineStarts := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	lineColumn := func(offset int) (int, int) {
+		i := sort.SearchInts(lineStarts, offset+1) - 1
+		return i + 1, utf8.RuneCount(src[lineStarts[i]:offset]) + 1
+	}
+
+	positions := make(map[*html.Node]Position)
+	for i, nodes := range elements {
+		if len(nodes) == 0 {
+			continue
+		}
+		pos := Position{Start: tags[i].start, End: ending[nodes[0]]}
+		pos.Line, pos.Column = lineColumn(pos.Start)
+		pos.EndLine, pos.EndColumn = lineColumn(pos.End)
+		for _, n := range nodes {
+			positions[n] = pos
+		}
+	}
+	return tree, positions, nil
+}
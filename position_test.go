@@ -0,0 +1,213 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestElementPositions(t *testing.T) {
+	src := []byte("<table>\n\t<tr><td>é<b class=\"x\">a</b></td></tr>\n</table><ul><li>1<li>2</ul><b class=\"x\">b</b><img src=\"x\">")
+	tree, positions, err := parsePositions(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	walkNodes(tree, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		pos, ok := positions[n]
+		if !ok {
+			result = append(result, n.Data)
+			return
+		}
+		result = append(result, fmt.Sprintf("%s %d-%d %d:%d-%d:%d", n.Data, pos.Start, pos.End, pos.Line, pos.Column, pos.EndLine, pos.EndColumn))
+	})
+	expected := []string{
+		"html", "head", "body",
+		"table 0-56 1:1-3:9",
+		"tbody",
+		"tr 9-47 2:2-2:39",
+		"td 13-42 2:6-2:34",
+		"b 19-37 2:11-2:29",
+		"ul 56-75 3:9-3:28",
+		"li 60-65 3:13-3:18",
+		"li 65-70 3:18-3:23",
+		"b 75-93 3:28-3:46",
+		"img 93-106 3:46-3:59",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, result)
+	}
+}
+
+func TestParsePositionsRecovery(t *testing.T) {
+	var testTable = []struct {
+		name     string
+		html     string
+		expected []string
+	}{
+		{
+			"merged body attributes",
+			`<body class="a"><p>x</p><body id="b" itemscope><span itemprop="n">1</span></body>`,
+			[]string{
+				`<body class="a"><p>x</p><body id="b" itemscope><span itemprop="n">1</span></body>`,
+				`<span itemprop="n">1</span>`,
+			},
+		},
+		{
+			"noscript and template content",
+			`<noscript><div itemscope></div></noscript><template><div itemscope><b itemprop="n">t</b></div></template><div itemscope><b itemprop="n">u</b></div>`,
+			[]string{
+				`<div itemscope><b itemprop="n">t</b></div>`,
+				`<b itemprop="n">t</b>`,
+				`<div itemscope><b itemprop="n">u</b></div>`,
+				`<b itemprop="n">u</b>`,
+			},
+		},
+		{
+			"foreign content",
+			`<svg><foreignObject itemscope><p itemprop="a">x</p></foreignObject></svg>`,
+			[]string{
+				`<foreignObject itemscope><p itemprop="a">x</p></foreignObject>`,
+				`<p itemprop="a">x</p>`,
+			},
+		},
+		{
+			"reconstructed formatting element",
+			`<div itemscope><a itemprop="url" href="/a"><p>one</a>two</p><a itemprop="url" href="/a">three</a></div>`,
+			[]string{
+				`<div itemscope><a itemprop="url" href="/a"><p>one</a>two</p><a itemprop="url" href="/a">three</a></div>`,
+				// The copy of the first link made by the parser shares its position.
+				`<a itemprop="url" href="/a">`,
+				`<a itemprop="url" href="/a">`,
+				`<a itemprop="url" href="/a">three</a>`,
+			},
+		},
+	}
+
+	for _, test := range testTable {
+		data, err := Parse(strings.NewReader(test.html), WithPositions())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result []string
+		span := func(pos *Position) string {
+			if pos == nil {
+				return "<nil>"
+			}
+			return test.html[pos.Start:pos.End]
+		}
+		for _, item := range data.Items {
+			result = append(result, span(item.Position))
+			for _, name := range item.propertyNames() {
+				for _, value := range item.Properties[name] {
+					result = append(result, span(value.Position))
+				}
+			}
+		}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Result should have been \"%v\" for %s, but it was \"%v\"", test.expected, test.name, result)
+		}
+	}
+}
+
+func TestParseWithPositions(t *testing.T) {
+	src := `<div itemscope itemtype="http://schema.org/Person">
+	<span itemprop="name">Jane</span>
+	<div itemprop="address" itemscope><span itemprop="addressLocality">Seattle</span></div>
+</div>`
+
+	doc, err := Extract(context.Background(), strings.NewReader(src),
+		WithSyntaxes(MicrodataSyntax), WithPositions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	person := doc.Microdata.Items[0]
+	name := person.Properties["name"][0]
+	address := person.Properties["address"][0]
+	locality := address.Item.Properties["addressLocality"][0]
+
+	checks := []struct {
+		pos      *Position
+		expected string
+	}{
+		{person.Position, src},
+		{name.Position, `<span itemprop="name">Jane</span>`},
+		{address.Position, `<div itemprop="address" itemscope><span itemprop="addressLocality">Seattle</span></div>`},
+		{address.Item.Position, `<div itemprop="address" itemscope><span itemprop="addressLocality">Seattle</span></div>`},
+		{locality.Position, `<span itemprop="addressLocality">Seattle</span>`},
+	}
+	for i, c := range checks {
+		if c.pos == nil {
+			t.Errorf("Result %d should have had a position", i)
+			continue
+		}
+		if result := src[c.pos.Start:c.pos.End]; result != c.expected {
+			t.Errorf("Result %d should have been \"%s\", but it was \"%s\"", i, c.expected, result)
+		}
+	}
+	if name.Position.Line != 2 || name.Position.Column != 2 {
+		t.Errorf("Result should have been at 2:2, but it was at %d:%d", name.Position.Line, name.Position.Column)
+	}
+
+	b, err := json.Marshal(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"position":{"start":0,"end":`) {
+		t.Errorf("Result should have had the position of the item, but it was \"%s\"", b)
+	}
+}
+
+func TestParseWithoutPositions(t *testing.T) {
+	data := ParseData(`<div itemscope><span itemprop="name">Jane</span></div>`, t)
+
+	item := data.Items[0]
+	if item.Position != nil || item.Properties["name"][0].Position != nil {
+		t.Error("Result should not have had positions")
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "position") {
+		t.Errorf("Result should not have had positions, but it was \"%s\"", b)
+	}
+}
+
+func TestParseKeepsSource(t *testing.T) {
+	var testTable = []struct {
+		opts     []ParseOption
+		expected bool
+	}{
+		{nil, false},
+		{[]ParseOption{WithStrictMode(), WithLimits(Limits{MaxItems: 1})}, false},
+		{[]ParseOption{WithPositions()}, true},
+		{[]ParseOption{WithDiagnostics(nil)}, true},
+	}
+
+	for _, test := range testTable {
+		cfg := newParseConfig(test.opts)
+		p, err := newParser(strings.NewReader(`<div itemscope></div>`), "utf-8", nil, cfg.keepSource())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := p.located != nil; result != test.expected {
+			t.Errorf("Source should have been kept: %v, but it was: %v", test.expected, result)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	rdfType   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	xsdPrefix = "http://www.w3.org/2001/XMLSchema#"
+)
+
+// termKind is the kind of an RDF term.
+type termKind int
+
+const (
+	iriTerm termKind = iota
+	blankTerm
+	literalTerm
+)
+
+// term is an RDF term: an IRI, a blank node label or a literal with an
+// optional datatype IRI.
+type term struct {
+	kind     termKind
+	value    string
+	datatype string
+}
+
+type triple struct {
+	subject, predicate, object term
+}
+
+// rdfDatatypes maps patterns of date, time and number values to their XML
+// Schema datatypes. Values matching none of the patterns are plain literals.
+var rdfDatatypes = []struct {
+	pattern  *regexp.Regexp
+	kind     ValueKind
+	datatype string
+}{
+	{regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`), DateTimeValue, "date"},
+	{regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?$`), DateTimeValue, "dateTime"},
+	{regexp.MustCompile(`^\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})?$`), DateTimeValue, "time"},
+	{regexp.MustCompile(`^\d{4}-\d{2}$`), DateTimeValue, "gYearMonth"},
+	{regexp.MustCompile(`^\d{4}$`), DateTimeValue, "gYear"},
+	{regexp.MustCompile(`^[+-]?\d+$`), NumberValue, "integer"},
+	{regexp.MustCompile(`^[+-]?(?:\d+\.?\d*|\.\d+)$`), NumberValue, "decimal"},
+	{regexp.MustCompile(`^[+-]?(?:\d+\.?\d*|\.\d+)[eE][+-]?\d+$`), NumberValue, "double"},
+}
+
+// rdfGenerator generates the triples of items, following the microdata to
+// RDF mapping.
+type rdfGenerator struct {
+	triples []triple
+	// subjects holds the subjects of the items already seen, so that shared
+	// and cyclic items are generated once.
+	subjects map[*Item]term
+	// pending holds the nested items whose triples are yet to be generated,
+	// with the vocabulary they inherit.
+	pending []pendingItem
+	blanks  int
+}
+
+type pendingItem struct {
+	item  *Item
+	vocab string
+}
+
+// triples returns the triples of the items. The triples of an item are
+// followed by those of the items nested in it.
+func (m *Microdata) triples() []triple {
+	g := &rdfGenerator{subjects: make(map[*Item]term)}
+	for _, item := range m.Items {
+		g.subject(item, "")
+		for len(g.pending) > 0 {
+			next := g.pending[0]
+			g.pending = g.pending[1:]
+			g.readItem(next.item, next.vocab)
+		}
+	}
+	return g.triples
+}
+
+// subject returns the subject of the given item: its ID, or a blank node for
+// items without ID. Items seen for the first time are queued for generation.
+func (g *rdfGenerator) subject(item *Item, vocab string) term {
+	if subject, ok := g.subjects[item]; ok {
+		return subject
+	}
+
+	subject := term{kind: iriTerm, value: item.ID}
+	if item.ID == "" {
+		subject = term{kind: blankTerm, value: fmt.Sprintf("b%d", g.blanks)}
+		g.blanks++
+	}
+	g.subjects[item] = subject
+	g.pending = append(g.pending, pendingItem{item, vocab})
+	return subject
+}
+
+// readItem generates the triples of the given item. The vocabulary of the
+// item is derived from its first type, or inherited from the enclosing item
+// when it has none.
+func (g *rdfGenerator) readItem(item *Item, vocab string) {
+	subject := g.subjects[item]
+
+	for _, t := range item.Types {
+		if isAbsoluteIRI(t) {
+			g.add(subject, term{kind: iriTerm, value: rdfType}, term{kind: iriTerm, value: t})
+		}
+	}
+	if len(item.Types) > 0 {
+		vocab = vocabulary(item.Types[0])
+	}
+
+	for _, name := range item.propertyNames() {
+		predicate := name
+		if !isAbsoluteIRI(name) {
+			if vocab == "" {
+				// Without vocabulary a property name has no IRI.
+				continue
+			}
+			predicate = vocab + name
+		}
+
+		for _, value := range item.Properties[name] {
+			var object term
+			switch value.Kind {
+			case ItemValue:
+				if value.Item == nil {
+					continue
+				}
+				object = g.subject(value.Item, vocab)
+			case URLValue:
+				object = term{kind: iriTerm, value: value.Raw}
+			default:
+				object = literal(value)
+			}
+			g.add(subject, term{kind: iriTerm, value: predicate}, object)
+		}
+	}
+}
+
+func (g *rdfGenerator) add(subject, predicate, object term) {
+	g.triples = append(g.triples, triple{subject, predicate, object})
+}
+
+// vocabulary returns the vocabulary of the given type: the type stripped of
+// everything following the last "#" or "/".
+func vocabulary(typ string) string {
+	if !isAbsoluteIRI(typ) {
+		return ""
+	}
+	if i := strings.LastIndexAny(typ, "#/"); i >= 0 {
+		return typ[:i+1]
+	}
+	return ""
+}
+
+// isAbsoluteIRI reports whether the given string is an absolute IRI.
+func isAbsoluteIRI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}
+
+// literal returns the literal of the given value, typed when the value is a
+// date, time or number in a lexical form of the XML Schema datatypes.
+func literal(value *Value) term {
+	t := term{kind: literalTerm, value: value.Raw}
+	s := strings.TrimSpace(value.Raw)
+	for _, d := range rdfDatatypes {
+		if d.kind == value.Kind && d.pattern.MatchString(s) {
+			t.value = s
+			t.datatype = xsdPrefix + d.datatype
+			break
+		}
+	}
+	return t
+}
+
+// WriteNTriples writes the items as RDF in the N-Triples format. Items
+// without ID become blank nodes. Types and property names that are not
+// absolute IRIs are expanded with the vocabulary of the item's first type;
+// properties of items without vocabulary that are not absolute IRIs are left
+// out. URL values become IRIs, and date, time and number values typed
+// literals.
+func (m *Microdata) WriteNTriples(w io.Writer) error {
+	return m.WriteNQuads(w, "")
+}
+
+// WriteNQuads writes the items as RDF in the N-Quads format, in the given
+// graph, usually the URL of the document. An empty graph is the default
+// graph. See WriteNTriples.
+func (m *Microdata) WriteNQuads(w io.Writer, graph string) error {
+	bw := bufio.NewWriter(w)
+	for _, t := range m.triples() {
+		bw.WriteString(ntriplesTerm(t.subject))
+		bw.WriteByte(' ')
+		bw.WriteString(ntriplesTerm(t.predicate))
+		bw.WriteByte(' ')
+		bw.WriteString(ntriplesTerm(t.object))
+		if graph != "" {
+			bw.WriteByte(' ')
+			bw.WriteString(ntriplesTerm(term{kind: iriTerm, value: graph}))
+		}
+		bw.WriteString(" .\n")
+	}
+	return bw.Flush()
+}
+
+// ntriplesTerm formats the given term as in N-Triples.
+func ntriplesTerm(t term) string {
+	switch t.kind {
+	case iriTerm:
+		return "<" + escapeIRI(t.value) + ">"
+	case blankTerm:
+		return "_:" + t.value
+	}
+	s := `"` + escapeLiteral(t.value) + `"`
+	if t.datatype != "" {
+		s += "^^<" + escapeIRI(t.datatype) + ">"
+	}
+	return s
+}
+
+// escapeIRI escapes the characters not allowed in IRI references.
+func escapeIRI(s string) string {
+	var buf strings.Builder
+	for _, r := range s {
+		if r <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r) {
+			fmt.Fprintf(&buf, "\\u%04X", r)
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+var literalReplacer = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// escapeLiteral escapes the characters not allowed in quoted literals.
+func escapeLiteral(s string) string {
+	return literalReplacer.Replace(s)
+}
+
+// turtlePrefixes holds the prefixes used to compact IRIs in Turtle, by
+// namespace.
+var turtlePrefixes = func() map[string]string {
+	prefixes := map[string]string{
+		"https://schema.org/": "schema",
+	}
+	for name, ns := range rdfaInitialContext {
+		// Prefer the shortest name, e.g. dc over dcterms.
+		if other, ok := prefixes[ns]; !ok || len(name) < len(other) || len(name) == len(other) && name < other {
+			prefixes[ns] = name
+		}
+	}
+	return prefixes
+}()
+
+// turtleLocalPattern matches the local names that are written in compact
+// form.
+var turtleLocalPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// turtleWriter compacts IRIs with the prefixes of the namespaces used.
+type turtleWriter struct {
+	// names maps the namespaces used to their prefix name.
+	names map[string]string
+	// taken holds the prefix names already used, by namespace.
+	taken map[string]string
+}
+
+// compact returns the compact form of the given IRI when its namespace has a
+// known prefix which is not taken by another namespace.
+func (tw *turtleWriter) compact(iri string) (string, bool) {
+	i := strings.LastIndexAny(iri, "#/")
+	if i < 0 {
+		return "", false
+	}
+	ns, local := iri[:i+1], iri[i+1:]
+	name, ok := turtlePrefixes[ns]
+	if !ok || !turtleLocalPattern.MatchString(local) {
+		return "", false
+	}
+	if other, taken := tw.taken[name]; taken && other != ns {
+		return "", false
+	}
+	tw.taken[name] = ns
+	tw.names[ns] = name
+	return name + ":" + local, true
+}
+
+// term formats the given term, compacting IRIs in predicate and datatype
+// positions and of types.
+func (tw *turtleWriter) term(t term, compact bool) string {
+	switch t.kind {
+	case iriTerm:
+		if compact {
+			if s, ok := tw.compact(t.value); ok {
+				return s
+			}
+		}
+	case literalTerm:
+		s := `"` + escapeLiteral(t.value) + `"`
+		if t.datatype != "" {
+			s += "^^" + tw.term(term{kind: iriTerm, value: t.datatype}, true)
+		}
+		return s
+	}
+	return ntriplesTerm(t)
+}
+
+// WriteTurtle writes the items as RDF in the Turtle format, grouping the
+// triples by subject and compacting the IRIs of well-known vocabularies, such
+// as schema.org, with prefixes. See WriteNTriples.
+func (m *Microdata) WriteTurtle(w io.Writer) error {
+	tw := &turtleWriter{
+		names: make(map[string]string),
+		taken: make(map[string]string),
+	}
+
+	var body strings.Builder
+	var last *term
+	for _, t := range m.triples() {
+		if last != nil && *last == t.subject {
+			body.WriteString(" ;\n\t")
+		} else {
+			if last != nil {
+				body.WriteString(" .\n\n")
+			}
+			body.WriteString(tw.term(t.subject, false))
+			body.WriteByte(' ')
+			subject := t.subject
+			last = &subject
+		}
+
+		if t.predicate.value == rdfType {
+			body.WriteString("a ")
+			body.WriteString(tw.term(t.object, true))
+			continue
+		}
+		body.WriteString(tw.term(t.predicate, true))
+		body.WriteByte(' ')
+		body.WriteString(tw.term(t.object, false))
+	}
+	if last != nil {
+		body.WriteString(" .\n")
+	}
+
+	namespaces := make([]string, 0, len(tw.names))
+	for ns := range tw.names {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return tw.names[namespaces[i]] < tw.names[namespaces[j]]
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, ns := range namespaces {
+		fmt.Fprintf(bw, "@prefix %s: <%s> .\n", tw.names[ns], escapeIRI(ns))
+	}
+	if len(namespaces) > 0 && body.Len() > 0 {
+		bw.WriteByte('\n')
+	}
+	bw.WriteString(body.String())
+	return bw.Flush()
+}
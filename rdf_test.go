@@ -0,0 +1,121 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var rdfSnippet = `
+<div itemscope itemtype="http://schema.org/Event" itemid="http://example.com/concert">
+	<span itemprop="name">The "Big" concert</span>
+	<time itemprop="startDate" datetime="2015-07-19T20:00:00Z">Sunday</time>
+	<a itemprop="url" href="http://example.com/tickets">Tickets</a>
+	<div itemprop="location" itemscope>
+		<span itemprop="name">Park</span>
+		<data itemprop="maximumAttendeeCapacity" value="500">five hundred</data>
+	</div>
+	<span itemprop="http://example.com/vocab#rating">5 stars</span>
+</div>
+<div itemscope>
+	<span itemprop="name">Without vocabulary</span>
+</div>`
+
+func parseRDFSnippet(t *testing.T) *Microdata {
+	data, err := ParseHTML(strings.NewReader(rdfSnippet), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestWriteNTriples(t *testing.T) {
+	var buf bytes.Buffer
+	if err := parseRDFSnippet(t).WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	result := buf.String()
+	expected := `<http://example.com/concert> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://schema.org/Event> .
+<http://example.com/concert> <http://example.com/vocab#rating> "5 stars" .
+<http://example.com/concert> <http://schema.org/location> _:b0 .
+<http://example.com/concert> <http://schema.org/name> "The \"Big\" concert" .
+<http://example.com/concert> <http://schema.org/startDate> "2015-07-19T20:00:00Z"^^<http://www.w3.org/2001/XMLSchema#dateTime> .
+<http://example.com/concert> <http://schema.org/url> <http://example.com/tickets> .
+_:b0 <http://schema.org/maximumAttendeeCapacity> "500"^^<http://www.w3.org/2001/XMLSchema#integer> .
+_:b0 <http://schema.org/name> "Park" .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestWriteNQuads(t *testing.T) {
+	var buf bytes.Buffer
+	if err := parseRDFSnippet(t).WriteNQuads(&buf, "http://example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("Result should have had 8 quads, but it had %d", len(lines))
+	}
+	for _, line := range lines {
+		if !strings.HasSuffix(line, " <http://example.com/> .") {
+			t.Errorf("Result should have been in the graph of the page, but it was \"%s\"", line)
+		}
+	}
+}
+
+func TestWriteTurtle(t *testing.T) {
+	var buf bytes.Buffer
+	if err := parseRDFSnippet(t).WriteTurtle(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	result := buf.String()
+	expected := `@prefix schema: <http://schema.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+<http://example.com/concert> a schema:Event ;
+	<http://example.com/vocab#rating> "5 stars" ;
+	schema:location _:b0 ;
+	schema:name "The \"Big\" concert" ;
+	schema:startDate "2015-07-19T20:00:00Z"^^xsd:dateTime ;
+	schema:url <http://example.com/tickets> .
+
+_:b0 schema:maximumAttendeeCapacity "500"^^xsd:integer ;
+	schema:name "Park" .
+`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestWriteNTriplesSharedItem(t *testing.T) {
+	shared := NewItem()
+	shared.addValue("name", &Value{Kind: TextValue, Raw: "Shared"})
+
+	a := NewItem()
+	a.addType("http://schema.org/Thing")
+	a.ID = "http://example.com/a"
+	a.addValue("about", &Value{Kind: ItemValue, Item: shared})
+	a.addValue("mentions", &Value{Kind: ItemValue, Item: shared})
+	shared.addValue("about", &Value{Kind: ItemValue, Item: a})
+
+	var buf bytes.Buffer
+	data := &Microdata{Items: []*Item{a}}
+	if err := data.WriteNTriples(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(buf.String(), "_:b1"); n != 0 {
+		t.Errorf("Result should have had a single blank node for the shared item, but it was \"%s\"", buf.String())
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 5 {
+		t.Errorf("Result should have had 5 triples, but it had %d", n)
+	}
+}
@@ -0,0 +1,410 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// rdfaInitialContext holds the prefixes of the RDFa 1.1 initial context that
+// are commonly found in RDFa Lite documents.
+var rdfaInitialContext = map[string]string{
+	"cc":      "http://creativecommons.org/ns#",
+	"dc":      "http://purl.org/dc/terms/",
+	"dcterms": "http://purl.org/dc/terms/",
+	"dc11":    "http://purl.org/dc/elements/1.1/",
+	"foaf":    "http://xmlns.com/foaf/0.1/",
+	"gr":      "http://purl.org/goodrelations/v1#",
+	"og":      "http://ogp.me/ns#",
+	"owl":     "http://www.w3.org/2002/07/owl#",
+	"rdf":     "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+	"rdfs":    "http://www.w3.org/2000/01/rdf-schema#",
+	"schema":  "http://schema.org/",
+	"sioc":    "http://rdfs.org/sioc/ns#",
+	"skos":    "http://www.w3.org/2004/02/skos/core#",
+	"v":       "http://rdf.data-vocabulary.org/#",
+	"vcard":   "http://www.w3.org/2006/vcard/ns#",
+	"xsd":     "http://www.w3.org/2001/XMLSchema#",
+}
+
+// rdfaContext holds the evaluation context of an element.
+type rdfaContext struct {
+	vocab    string
+	prefixes map[string]string
+	// subject is the item that properties of the element's descendants
+	// belong to, or nil outside of items.
+	subject *Item
+	// depth is the nesting depth of the subject, starting at 1.
+	depth int
+}
+
+// rdfaFrame is an element to read, with its evaluation context, or, when done
+// is set, a function to call once the descendants of an element are read.
+type rdfaFrame struct {
+	node *html.Node
+	ctx  *rdfaContext
+	done func()
+}
+
+type rdfaParser struct {
+	tree    *html.Node
+	data    *Microdata
+	baseURL *url.URL
+	// stack holds the elements to read, using an explicit stack so deeply
+	// nested markup does not exhaust the goroutine stack.
+	stack []rdfaFrame
+	// limits caps the work done by the parser.
+	limits Limits
+	// itemCount is the number of items read.
+	itemCount int
+	// values holds the number of property values of the items.
+	values map[*Item]int
+}
+
+// newRDFaParser returns an RDFa Lite parser for the given node tree. A nil
+// baseURL is treated as the empty URL.
+func newRDFaParser(tree *html.Node, baseURL *url.URL) *rdfaParser {
+	if baseURL == nil {
+		baseURL = &url.URL{}
+	}
+
+	return &rdfaParser{
+		tree:    tree,
+		data:    &Microdata{},
+		baseURL: baseURL,
+		values:  make(map[*Item]int),
+	}
+}
+
+// parse returns the items described by the RDFa Lite attributes of the
+// parser's node tree.
+func (p *rdfaParser) parse() (*Microdata, error) {
+	p.baseURL = documentBaseURL(p.tree, p.baseURL)
+
+	p.stack = []rdfaFrame{{node: p.tree, ctx: &rdfaContext{prefixes: rdfaInitialContext}}}
+	for len(p.stack) > 0 {
+		f := p.stack[len(p.stack)-1]
+		p.stack = p.stack[:len(p.stack)-1]
+		if f.done != nil {
+			f.done()
+			continue
+		}
+		p.readElement(f.node, f.ctx)
+	}
+	return p.data, nil
+}
+
+// readElement applies the RDFa Lite attributes of the given node, in the
+// given evaluation context, and schedules the reading of its descendants.
+func (p *rdfaParser) readElement(node *html.Node, ctx *rdfaContext) {
+	if node.Type == html.ElementNode {
+		ctx = p.localContext(node, ctx)
+
+		properties := p.expandAll(getAttrFields("property", node), ctx)
+		typeofs, hasTypeof := getAttr("typeof", node)
+		resource, hasResource := getAttr("resource", node)
+
+		switch {
+		case hasTypeof || hasResource && len(properties) == 0:
+			// The element describes a new item.
+			item := p.openItem(node, ctx)
+			if item == nil {
+				return
+			}
+			for _, t := range p.expandAll(strings.Fields(typeofs), ctx) {
+				item.addType(t)
+			}
+			if hasResource {
+				item.ID, _ = resolveURL(p.baseURL, p.expandCURIE(resource, ctx))
+			}
+
+			p.readChildren(node, ctx.withSubject(item), func() {
+				if !hasTypeof && len(item.Properties) == 0 {
+					// A bare resource without statements about it.
+					return
+				}
+
+				value := &Value{Kind: ItemValue, Item: item, Element: node.Data}
+				if ctx.subject != nil && len(properties) > 0 {
+					p.addValues(ctx.subject, node, properties, value)
+				} else {
+					p.data.addItem(item)
+				}
+			})
+			return
+		case len(properties) > 0 && hasResource:
+			// The element links to a resource, which descendants describe.
+			item := p.openItem(node, ctx)
+			if item == nil {
+				return
+			}
+			item.ID, _ = resolveURL(p.baseURL, p.expandCURIE(resource, ctx))
+
+			p.readChildren(node, ctx.withSubject(item), func() {
+				value := &Value{Kind: ItemValue, Item: item, Element: node.Data}
+				if len(item.Properties) == 0 {
+					value = &Value{Kind: URLValue, Raw: item.ID, Element: node.Data}
+				}
+				if ctx.subject != nil {
+					p.addValues(ctx.subject, node, properties, value)
+				}
+			})
+			return
+		case len(properties) > 0 && ctx.subject != nil:
+			if value := p.getValue(node); len(value.Raw) > 0 {
+				p.addValues(ctx.subject, node, properties, value)
+			}
+		}
+	}
+
+	p.readChildren(node, ctx, nil)
+}
+
+// readChildren schedules the reading of the children of the given node, in
+// the given context, followed by a call to done, if set.
+func (p *rdfaParser) readChildren(node *html.Node, ctx *rdfaContext, done func()) {
+	if done != nil {
+		p.stack = append(p.stack, rdfaFrame{done: done})
+	}
+	for c := node.LastChild; c != nil; c = c.PrevSibling {
+		p.stack = append(p.stack, rdfaFrame{node: c, ctx: ctx})
+	}
+}
+
+// openItem returns a new item for the given element, or nil when it exceeds
+// the depth or item limit, in which case the element and its descendants are
+// skipped.
+func (p *rdfaParser) openItem(node *html.Node, ctx *rdfaContext) *Item {
+	if max := p.limits.MaxDepth; max > 0 && ctx.depth >= max {
+		p.warn(DiagLimitExceeded, node, fmt.Sprintf("item nested deeper than %d levels; item skipped", max))
+		return nil
+	}
+	if max := p.limits.MaxItems; max > 0 && p.itemCount >= max {
+		if p.itemCount == max {
+			p.warn(DiagLimitExceeded, node, fmt.Sprintf("document has more than %d items; remaining items skipped", max))
+			p.itemCount++
+		}
+		return nil
+	}
+	p.itemCount++
+	return NewItem()
+}
+
+// warn records a diagnostic for the given node.
+func (p *rdfaParser) warn(code string, node *html.Node, message string) {
+	p.data.addDiagnostic(Diagnostic{
+		Code:    code,
+		Message: message,
+		Node:    node,
+	})
+}
+
+// addValues adds the value, read from the given element, to the given item
+// for each of the properties, up to the property value limit.
+func (p *rdfaParser) addValues(item *Item, node *html.Node, properties []string, value *Value) {
+	for _, property := range properties {
+		if max := p.limits.MaxProperties; max > 0 && p.values[item] >= max {
+			if p.values[item] == max {
+				p.warn(DiagLimitExceeded, node, fmt.Sprintf("item has more than %d property values; remaining properties skipped", max))
+				p.values[item]++
+			}
+			return
+		}
+		item.addValue(property, value)
+		p.values[item]++
+	}
+}
+
+// localContext returns the evaluation context of the given element, applying
+// its vocab and prefix attributes to the context of its parent.
+func (p *rdfaParser) localContext(node *html.Node, ctx *rdfaContext) *rdfaContext {
+	vocab, hasVocab := getAttr("vocab", node)
+	prefix, hasPrefix := getAttr("prefix", node)
+	if !hasVocab && !hasPrefix {
+		return ctx
+	}
+
+	local := *ctx
+	if hasVocab {
+		local.vocab = strings.TrimSpace(vocab)
+	}
+	if hasPrefix {
+		local.prefixes = make(map[string]string, len(ctx.prefixes))
+		for k, v := range ctx.prefixes {
+			local.prefixes[k] = v
+		}
+		// The prefix attribute holds pairs such as "og: http://ogp.me/ns#".
+		fields := strings.Fields(prefix)
+		for i := 0; i+1 < len(fields); i++ {
+			if name := fields[i]; strings.HasSuffix(name, ":") {
+				local.prefixes[strings.ToLower(strings.TrimSuffix(name, ":"))] = fields[i+1]
+				i++
+			}
+		}
+	}
+	return &local
+}
+
+// withSubject returns a copy of the context with the given subject, nested
+// in the subject of the context.
+func (ctx *rdfaContext) withSubject(subject *Item) *rdfaContext {
+	local := *ctx
+	local.subject = subject
+	local.depth++
+	return &local
+}
+
+// expandAll expands the given terms, CURIEs and IRIs, dropping terms that
+// cannot be expanded.
+func (p *rdfaParser) expandAll(values []string, ctx *rdfaContext) []string {
+	var result []string
+	for _, v := range values {
+		if iri, ok := p.expand(v, ctx); ok {
+			result = append(result, iri)
+		}
+	}
+	return result
+}
+
+// expand expands a term using the vocabulary, a CURIE using the prefixes, and
+// returns absolute IRIs unchanged. Terms are undefined, and not expanded, in
+// the absence of a vocabulary.
+func (p *rdfaParser) expand(value string, ctx *rdfaContext) (string, bool) {
+	if strings.Contains(value, ":") {
+		return p.expandCURIE(value, ctx), true
+	}
+	if ctx.vocab == "" {
+		return "", false
+	}
+	return ctx.vocab + value, true
+}
+
+// expandCURIE expands a CURIE with a known prefix. Other values are returned
+// unchanged.
+func (p *rdfaParser) expandCURIE(value string, ctx *rdfaContext) string {
+	if i := strings.IndexByte(value, ':'); i >= 0 && !strings.HasPrefix(value[i+1:], "//") {
+		if iri, ok := ctx.prefixes[strings.ToLower(value[:i])]; ok {
+			return iri + value[i+1:]
+		}
+	}
+	return value
+}
+
+// getValue returns the value of a property element that does not describe an
+// item, truncated to the value length limit.
+func (p *rdfaParser) getValue(node *html.Node) *Value {
+	value := p.readValue(node)
+	if max := p.limits.MaxValueLength; max > 0 && len(value.Raw) > max {
+		p.warn(DiagLimitExceeded, node, fmt.Sprintf("value longer than %d bytes; value truncated", max))
+		value.Raw = truncate(value.Raw, max)
+	}
+	return value
+}
+
+// readValue returns the value of a property element that does not describe
+// an item.
+func (p *rdfaParser) readValue(node *html.Node) *Value {
+	value := &Value{Kind: TextValue, Element: node.Data}
+
+	if content, ok := getAttr("content", node); ok {
+		value.Raw = content
+		return value
+	}
+
+	for _, attr := range []string{"href", "src"} {
+		if s, ok := getAttr(attr, node); ok {
+			value.Kind = URLValue
+			value.Raw, _ = resolveURL(p.baseURL, s)
+			return value
+		}
+	}
+
+	if node.DataAtom == atom.Time {
+		value.Kind = DateTimeValue
+		if s, ok := getAttr("datetime", node); ok {
+			value.Raw = s
+			return value
+		}
+	}
+
+	var buf bytes.Buffer
+	walkNodes(node, func(n *html.Node) {
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+	})
+	value.Raw = buf.String()
+	return value
+}
+
+// getAttrFields returns the whitespace separated tokens of the given
+// attribute.
+func getAttrFields(attribute string, node *html.Node) []string {
+	s, _ := getAttr(attribute, node)
+	return strings.Fields(s)
+}
+
+// ParseRDFaTree returns the items described by the RDFa Lite 1.1 attributes,
+// vocab, typeof, property, resource and prefix, of the given HTML document.
+// Types and property names are expanded to IRIs. The given url is used to
+// resolve the URLs, unless the document contains a base element. It is
+// equivalent to ParseRDFaTreeWith with WithBaseURL.
+func ParseRDFaTree(tree *html.Node, u *url.URL) (*Microdata, error) {
+	return ParseRDFaTreeWith(tree, WithBaseURL(u))
+}
+
+// ParseRDFaTreeWith returns the items described by the RDFa Lite attributes of
+// the given HTML document. See ParseRDFaTree. WithBaseURL sets the URL used
+// to resolve the URLs, WithLimits caps the work done, and the handler of
+// WithDiagnostics is called with each diagnostic, without line and column.
+func ParseRDFaTreeWith(tree *html.Node, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+	data, err := parseRDFaTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(nil, data)
+	return data, nil
+}
+
+// ParseRDFa parses the HTML document available in the given reader and returns
+// the items described by its RDFa Lite attributes. See ParseRDFaTree and
+// ParseHTML. It is equivalent to ParseRDFaWith with WithContentType and
+// WithBaseURL.
+func ParseRDFa(r io.Reader, contentType string, u *url.URL) (*Microdata, error) {
+	return ParseRDFaWith(r, WithContentType(contentType), WithBaseURL(u))
+}
+
+// ParseRDFaWith parses the HTML document available in the given reader and
+// returns the items described by its RDFa Lite attributes. WithBaseURL and
+// WithContentType apply as with Parse, WithLimits caps the work done, and
+// WithDiagnostics locates the diagnostics in the source.
+func ParseRDFaWith(r io.Reader, opts ...ParseOption) (*Microdata, error) {
+	cfg := newParseConfig(opts)
+	tree, positions, err := parseSource(r, cfg.contentType, cfg.keepSource())
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseRDFaTree(tree, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.settleDiagnostics(positions, data)
+	return data, nil
+}
+
+// parseRDFaTree returns the RDFa Lite items of the given node tree with the
+// given configuration, leaving the diagnostics to the caller.
+func parseRDFaTree(tree *html.Node, cfg *parseConfig) (*Microdata, error) {
+	p := newRDFaParser(tree, cfg.baseURL)
+	p.limits = cfg.limits
+	return p.parse()
+}
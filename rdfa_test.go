@@ -0,0 +1,133 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+var rdfaSnippet = `
+<html prefix="ex: http://example.com/vocab#">
+	<body vocab="http://schema.org/">
+		<div typeof="Person" resource="#manu">
+			<span property="name">Manu Sporny</span>
+			<a property="url" href="/manu">Homepage</a>
+			<img property="image" src="manu.jpg" />
+			<meta property="ex:nickname" content="msporny" />
+			<span property="unknown:term">Unknown prefix</span>
+			<div property="knows" typeof="Person">
+				<span property="name">Gregg Kellogg</span>
+			</div>
+			<span property="worksFor" resource="#digitalbazaar">
+				<span property="name">Digital Bazaar</span>
+			</span>
+			<span property="memberOf" resource="http://www.w3.org/"></span>
+			<time property="birthDate" datetime="1978-01-01">in 1978</time>
+		</div>
+		<p property="name">Not part of an item</p>
+	</body>
+</html>`
+
+func TestParseRDFa(t *testing.T) {
+	u, _ := url.Parse("http://example.com/people")
+	data, err := ParseRDFa(strings.NewReader(rdfaSnippet), "text/html", u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["http://schema.org/Person"],"properties":{` +
+		`"http://example.com/vocab#nickname":["msporny"],` +
+		`"http://schema.org/birthDate":["1978-01-01"],` +
+		`"http://schema.org/image":["http://example.com/manu.jpg"],` +
+		`"http://schema.org/knows":[{"type":["http://schema.org/Person"],"properties":{"http://schema.org/name":["Gregg Kellogg"]}}],` +
+		`"http://schema.org/memberOf":["http://www.w3.org/"],` +
+		`"http://schema.org/name":["Manu Sporny"],` +
+		`"http://schema.org/url":["http://example.com/manu"],` +
+		`"http://schema.org/worksFor":[{"type":[],"properties":{"http://schema.org/name":["Digital Bazaar"]},"id":"http://example.com/people#digitalbazaar"}],` +
+		`"unknown:term":["Unknown prefix"]` +
+		`},"id":"http://example.com/people#manu"}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseRDFaValueKind(t *testing.T) {
+	data, err := ParseRDFa(strings.NewReader(rdfaSnippet), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		path string
+		kind ValueKind
+	}{
+		{"http://schema.org/name", TextValue},
+		{"http://schema.org/url", URLValue},
+		{"http://schema.org/memberOf", URLValue},
+		{"http://schema.org/birthDate", DateTimeValue},
+		{"http://schema.org/knows", ItemValue},
+	}
+
+	for _, test := range testTable {
+		if result := data.Items[0].Get(test.path).Kind; result != test.kind {
+			t.Errorf("Result should have been \"%s\" for \"%s\", but it was \"%s\"", test.kind, test.path, result)
+		}
+	}
+}
+
+func TestParseRDFaWithoutVocab(t *testing.T) {
+	html := `
+		<div typeof="schema:Book">
+			<span property="name">Undefined term</span>
+			<span property="schema:name">The Black Cloud</span>
+		</div>`
+
+	data, err := ParseRDFa(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["http://schema.org/Book"],"properties":{"http://schema.org/name":["The Black Cloud"]}}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseRDFaWith(t *testing.T) {
+	html := "<div vocab=\"http://schema.org/\" typeof=\"Person\" resource=\"#caf\xe9\">" +
+		"<span property=\"name\">Caf\xe9</span><span property=\"jobTitle\">Owner</span></div>"
+
+	u, _ := url.Parse("http://example.com/people")
+	data, err := ParseRDFaWith(strings.NewReader(html), WithBaseURL(u),
+		WithContentType("text/html; charset=iso-8859-1"), WithLimits(Limits{MaxProperties: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := string(b)
+	expected := `{"items":[{"type":["http://schema.org/Person"],"properties":{"http://schema.org/name":["Café"]},"id":"http://example.com/people#caf%C3%A9"}]}`
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+	if len(data.Diagnostics) != 1 || data.Diagnostics[0].Code != DiagLimitExceeded {
+		t.Errorf("Result should have had a limit diagnostic, but it had %v", data.Diagnostics)
+	}
+}
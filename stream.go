@@ -0,0 +1,484 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ParseStream reads the HTML document available in the given reader with a
+// tokenizer instead of building its node tree, and calls fn with each
+// top-level item as soon as the element of the item is closed. Only the
+// elements that may contribute to an item are kept in memory, so the memory
+// used depends on the size of the items rather than the size of the
+// document.
+//
+// Elements with an id are kept after they are closed, for the itemref
+// attributes of the items following them, up to the 1024 most recently closed
+// ones; older ones are dropped, unless a buffered item references them, and
+// itemref tokens matching them are then unresolved. An item whose itemref
+// attributes reference an element that is not closed yet, usually an element
+// further in the document, is buffered until the element is closed, or until
+// the end of the document. Items are therefore
+// passed to fn in the order they are completed, which is not the order of
+// ParseHTML for nested top-level items and forward references. Items shared
+// between top-level items through itemref are converted once for each of
+// them.
+//
+// The tokenizer does not recover from misnested markup the way the HTML
+// parser does: end tags close the matching open element, and the start tags
+// of elements such as div and li only close the open p and li elements.
+//
+// The arguments u and contentType are used as with ParseHTML; a base element
+// applies to the items following it. It is equivalent to ParseStreamWith with
+// WithContentType and WithBaseURL followed by the given options, so that
+// WithContentType and WithBaseURL among them take precedence over the
+// arguments.
+func ParseStream(r io.Reader, contentType string, u *url.URL, fn func(*Item) error, opts ...ParseOption) ([]Diagnostic, error) {
+	opts = append([]ParseOption{WithContentType(contentType), WithBaseURL(u)}, opts...)
+	return ParseStreamWith(r, fn, opts...)
+}
+
+// ParseStreamWith reads the HTML document available in the given reader as
+// ParseStream does. WithBaseURL and WithContentType apply as with Parse; a
+// base element applies to the items following it. WithPositions records the
+// positions of the items and values, WithLimits caps the work done, and the
+// handler of WithDiagnostics is called with each diagnostic as soon as it is
+// found. When fn returns an error, ParseStreamWith stops and returns the
+// error. ParseStreamWith returns the diagnostics found in the markup, with
+// their line and column, unless a handler is set with WithDiagnostics: the
+// diagnostics are then only passed to the handler, so that they are not held
+// in memory. The returned diagnostics have no Node, as the elements are
+// released once read.
+func ParseStreamWith(r io.Reader, fn func(*Item) error, opts ...ParseOption) ([]Diagnostic, error) {
+	cfg := newParseConfig(opts)
+
+	r, err := utf8Reader(r, cfg.contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newStreamParser(cfg.baseURL, fn)
+	s.configure(cfg)
+	if cfg.positions {
+		s.positions = s.spans
+	}
+	err = s.run(r)
+	return s.data.Diagnostics, err
+}
+
+// streamElement is an open element of the stream parser.
+type streamElement struct {
+	node *html.Node
+	// topLevel reports whether the element is the itemscope element of a
+	// top-level item.
+	topLevel bool
+	// inProp, inScope and inID report whether the element, or one of its
+	// ancestors, has an itemprop, itemscope or id attribute.
+	inProp, inScope, inID bool
+	// relevant reports whether the element, or one of its descendants, has
+	// an itemprop, itemscope or id attribute.
+	relevant bool
+}
+
+// cursor is a position in the source.
+type cursor struct {
+	offset, line, column int
+}
+
+type streamParser struct {
+	*parser
+	fn func(*Item) error
+
+	// stack holds the open elements, starting with the document node.
+	stack []*streamElement
+	// open holds the open elements by node.
+	open map[*html.Node]bool
+	// counts holds the number of open elements by name.
+	counts map[atom.Atom]int
+	// spans holds the positions of the elements kept in memory.
+	spans map[*html.Node]Position
+	// buffered holds the itemscope elements of the top-level items waiting
+	// for the elements referenced through itemref to be closed.
+	buffered []*html.Node
+	// identified holds the closed elements with an id kept for itemref,
+	// oldest first, and maxIdentified the number of them kept.
+	identified    []*html.Node
+	maxIdentified int
+
+	order   int
+	base    bool
+	current cursor
+}
+
+// maxStreamIdentified is the number of closed elements with an id kept by the
+// stream parser for itemref, besides those referenced by buffered items.
+const maxStreamIdentified = 1024
+
+// newStreamParser returns a stream parser passing the items to fn. A nil
+// baseURL is treated as the empty URL.
+func newStreamParser(baseURL *url.URL, fn func(*Item) error) *streamParser {
+	doc := &html.Node{Type: html.DocumentNode}
+	return &streamParser{
+		parser:        newTreeParser(doc, baseURL),
+		fn:            fn,
+		stack:         []*streamElement{{node: doc}},
+		open:          make(map[*html.Node]bool),
+		counts:        make(map[atom.Atom]int),
+		spans:         make(map[*html.Node]Position),
+		maxIdentified: maxStreamIdentified,
+		current:       cursor{line: 1, column: 1},
+	}
+}
+
+// run tokenizes the document and passes the items to fn.
+func (s *streamParser) run(r io.Reader) error {
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if err := z.Err(); err != io.EOF {
+				return err
+			}
+			break
+		}
+
+		start := s.current
+		s.advance(z.Raw())
+
+		var err error
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			i := impliedEnd(len(s.stack), func(i int) atom.Atom { return s.stack[i].node.DataAtom }, s.counts, tok.DataAtom)
+			for i > 0 && len(s.stack) > i && err == nil {
+				err = s.pop(start)
+			}
+			if err != nil {
+				return err
+			}
+			s.push(tok, start)
+			if tt == html.SelfClosingTagToken || voidElements[tok.DataAtom] {
+				err = s.pop(s.current)
+			}
+		case html.EndTagToken:
+			tok := z.Token()
+			for i := len(s.stack) - 1; i > 0; i-- {
+				if s.stack[i].node.Data != tok.Data {
+					continue
+				}
+				for len(s.stack) > i+1 && err == nil {
+					err = s.pop(start)
+				}
+				if err == nil {
+					err = s.pop(s.current)
+				}
+				break
+			}
+		case html.TextToken:
+			if top := s.stack[len(s.stack)-1]; top.inProp {
+				top.node.AppendChild(&html.Node{Type: html.TextNode, Data: z.Token().Data})
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for len(s.stack) > 1 {
+		if err := s.pop(s.current); err != nil {
+			return err
+		}
+	}
+
+	// The elements referenced by the buffered items are not in the document.
+	buffered := s.buffered
+	s.buffered = nil
+	for _, node := range buffered {
+		if err := s.emit(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// advance moves the cursor past the given source.
+func (s *streamParser) advance(raw []byte) {
+	for _, b := range raw {
+		switch {
+		case b == '\n':
+			s.current.line++
+			s.current.column = 1
+		case b&0xc0 != 0x80:
+			// Continuation bytes do not start a character.
+			s.current.column++
+		}
+	}
+	s.current.offset += len(raw)
+}
+
+// push opens the element of the given start tag, starting at the given
+// position.
+func (s *streamParser) push(tok html.Token, start cursor) {
+	parent := s.stack[len(s.stack)-1]
+	n := &html.Node{
+		Type:     html.ElementNode,
+		Data:     tok.Data,
+		DataAtom: tok.DataAtom,
+		Attr:     tok.Attr,
+	}
+	parent.node.AppendChild(n)
+
+	s.treeOrder[n] = s.order
+	s.order++
+	s.spans[n] = Position{Start: start.offset, Line: start.line, Column: start.column}
+	s.open[n] = true
+	s.counts[n.DataAtom]++
+
+	_, scope := getAttr("itemscope", n)
+	_, prop := getAttr("itemprop", n)
+	id, hasID := getAttr("id", n)
+	s.stack = append(s.stack, &streamElement{
+		node:     n,
+		topLevel: scope && !prop,
+		inProp:   parent.inProp || prop,
+		inScope:  parent.inScope || scope,
+		inID:     parent.inID || hasID,
+		relevant: scope || prop || hasID,
+	})
+
+	if hasID {
+		if _, ok := s.identifiedNodes[id]; ok {
+			from := len(s.data.Diagnostics)
+			s.warn(DiagDuplicateID, n, fmt.Sprintf("duplicate id %q; the first element with the id is used", id))
+			s.locate(from)
+		} else {
+			s.identifiedNodes[id] = n
+		}
+	}
+
+	// As documentBaseURL, the first base element with an href sets the
+	// base URL.
+	if n.DataAtom == atom.Base && !s.base {
+		if href, ok := getAttr("href", n); ok {
+			s.base = true
+			if b, err := s.baseURL.Parse(strings.TrimSpace(href)); err == nil {
+				s.baseURL = b
+			}
+		}
+	}
+}
+
+// pop closes the innermost open element, ending at the given position. The
+// element is kept in its parent when an item may need it; the itemscope
+// element of a top-level item is passed to fn, or buffered.
+func (s *streamParser) pop(end cursor) error {
+	e := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	parent := s.stack[len(s.stack)-1]
+	delete(s.open, e.node)
+	s.counts[e.node.DataAtom]--
+
+	pos := s.spans[e.node]
+	pos.End, pos.EndLine, pos.EndColumn = end.offset, end.line, end.column
+	s.spans[e.node] = pos
+
+	switch {
+	case e.topLevel:
+		// The crawl does not descend into itemscope elements, so the item
+		// is only kept for the text of an enclosing property.
+		if !parent.inProp {
+			parent.node.RemoveChild(e.node)
+		}
+		if s.ready(e.node) {
+			if err := s.emit(e.node); err != nil {
+				return err
+			}
+		} else {
+			s.buffered = append(s.buffered, e.node)
+		}
+	case parent.inProp || parent.inScope || e.relevant && parent.inID:
+		if e.relevant {
+			parent.relevant = true
+		}
+	default:
+		parent.node.RemoveChild(e.node)
+		s.release(e.node)
+	}
+
+	if id, ok := getAttr("id", e.node); ok && s.identifiedNodes[id] == e.node {
+		s.identified = append(s.identified, e.node)
+		if len(s.buffered) > 0 {
+			if err := s.flush(); err != nil {
+				return err
+			}
+		}
+		s.forget()
+	}
+	return nil
+}
+
+// forget drops the oldest closed elements with an id beyond maxIdentified,
+// except those referenced by buffered items.
+func (s *streamParser) forget() {
+	excess := len(s.identified) - s.maxIdentified
+	if excess <= 0 {
+		return
+	}
+
+	referenced := make(map[string]bool)
+	for _, root := range s.buffered {
+		walkNodes(root, func(n *html.Node) {
+			if refs, ok := getAttr("itemref", n); ok {
+				for _, id := range s.tokens(refs) {
+					referenced[id] = true
+				}
+			}
+		})
+	}
+
+	kept := s.identified[:0]
+	for _, n := range s.identified {
+		id, _ := getAttr("id", n)
+		if excess == 0 || referenced[id] {
+			kept = append(kept, n)
+			continue
+		}
+		excess--
+		delete(s.identifiedNodes, id)
+		if !s.retained(n) {
+			s.release(n)
+		}
+	}
+	for i := len(kept); i < len(s.identified); i++ {
+		s.identified[i] = nil
+	}
+	s.identified = kept
+}
+
+// retained reports whether the given element is still kept in memory by one
+// of its ancestors: the document, an element with an id kept for itemref, or
+// a buffered item. It is released with that ancestor.
+func (s *streamParser) retained(n *html.Node) bool {
+	for a := n.Parent; a != nil; a = a.Parent {
+		if a == s.stack[0].node {
+			return true
+		}
+		if id, ok := getAttr("id", a); ok && s.identifiedNodes[id] == a {
+			return true
+		}
+		for _, root := range s.buffered {
+			if a == root {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ready reports whether the elements referenced through itemref by the item
+// of the given itemscope element, and by the items nested in it, are closed.
+func (s *streamParser) ready(root *html.Node) bool {
+	seen := make(map[*html.Node]bool)
+	pending := []*html.Node{root}
+	for len(pending) > 0 {
+		n := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+
+		if refs, ok := getAttr("itemref", n); ok {
+			for _, id := range s.tokens(refs) {
+				ref, ok := s.identifiedNodes[id]
+				if !ok || s.open[ref] {
+					return false
+				}
+				pending = append(pending, ref)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				pending = append(pending, c)
+			}
+		}
+	}
+	return true
+}
+
+// flush passes the buffered items that are ready to fn.
+func (s *streamParser) flush() error {
+	var waiting []*html.Node
+	for i, node := range s.buffered {
+		if !s.ready(node) {
+			waiting = append(waiting, node)
+			continue
+		}
+		if err := s.emit(node); err != nil {
+			s.buffered = append(waiting, s.buffered[i+1:]...)
+			return err
+		}
+	}
+	s.buffered = waiting
+	return nil
+}
+
+// emit converts the item of the given itemscope element and passes it to fn.
+func (s *streamParser) emit(root *html.Node) error {
+	from := len(s.data.Diagnostics)
+	item := s.readItem(root)
+	s.locate(from)
+
+	s.items = make(map[*html.Node]*Item)
+	s.release(root)
+	if item == nil {
+		// The item limit is reached.
+		return nil
+	}
+	return s.fn(item)
+}
+
+// locate sets the line and column of the diagnostics starting at the given
+// index, and reports them. The diagnostics no longer refer to their elements
+// afterwards, and are dropped once reported.
+func (s *streamParser) locate(from int) {
+	for i := from; i < len(s.data.Diagnostics); i++ {
+		d := &s.data.Diagnostics[i]
+		if pos, ok := s.spans[d.Node]; ok {
+			d.Line, d.Column = pos.Line, pos.Column
+		}
+		if s.report != nil {
+			s.report(*d)
+		}
+		d.Node = nil
+	}
+	if s.report != nil {
+		s.data.Diagnostics = s.data.Diagnostics[:from]
+	}
+}
+
+// release forgets the given element and its descendants, except for the
+// elements that can be referenced through itemref.
+func (s *streamParser) release(root *html.Node) {
+	pending := []*html.Node{root}
+	for len(pending) > 0 {
+		n := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if id, ok := getAttr("id", n); ok && s.identifiedNodes[id] == n {
+			continue
+		}
+		delete(s.treeOrder, n)
+		delete(s.spans, n)
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			pending = append(pending, c)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// parseStreamItems returns the items streamed from the given HTML.
+func parseStreamItems(html string, t *testing.T, opts ...ParseOption) ([]*Item, []Diagnostic) {
+	u, _ := url.Parse("http://example.com/")
+	var items []*Item
+	diagnostics, err := ParseStream(strings.NewReader(html), "text/html", u, func(item *Item) error {
+		items = append(items, item)
+		return nil
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return items, diagnostics
+}
+
+func TestParseStreamMatchesParseHTML(t *testing.T) {
+	html := `<!DOCTYPE html>
+		<html><head><base href="/catalogue/"></head><body>
+		<ul>
+			<li itemscope itemtype="http://schema.org/Product">
+				<a itemprop="url" href="p1"><span itemprop="name">One &amp; only</span></a>
+				<p itemprop="description">First<br>line
+				<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+					<data itemprop="price" value="9.99">€9.99</data>
+					<meta itemprop="priceCurrency" content="EUR">
+				</div>
+			<li itemscope itemtype="http://schema.org/Product" itemref="brand">
+				<img itemprop="image" src="p2.jpg"><span itemprop="name">Two</span>
+		</ul>
+		<p id="brand" itemprop="brand">ACME</p>
+		<div itemscope><time itemprop="date" datetime="2015-07-19">Sunday</time></div>
+		</body></html>`
+
+	items, diagnostics := parseStreamItems(html, t)
+	if len(diagnostics) != 0 {
+		t.Errorf("Result should not have had diagnostics, but it had \"%v\"", diagnostics)
+	}
+
+	data := ParseData(html, t)
+	expected, _ := json.Marshal(data.Items)
+	result, _ := json.Marshal(items)
+	if string(result) != string(expected) {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseStreamForwardReference(t *testing.T) {
+	html := `
+		<div itemscope itemref="later"><span itemprop="name">First</span></div>
+		<div itemscope><span itemprop="name">Second</span></div>
+		<div id="later"><span itemprop="author">Jane</span></div>
+		<div itemscope itemref="missing"><span itemprop="name">Third</span></div>`
+
+	items, diagnostics := parseStreamItems(html, t)
+
+	var result []string
+	for _, item := range items {
+		result = append(result, item.GetString("name")+":"+item.GetString("author"))
+	}
+	expected := []string{"Second:", "First:Jane", "Third:"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, result)
+	}
+
+	if len(diagnostics) != 1 || diagnostics[0].Code != DiagUnresolvedItemref || diagnostics[0].Line != 5 || diagnostics[0].Column != 3 {
+		t.Errorf("Result should have had an unresolved itemref at 5:3, but it had \"%v\"", diagnostics)
+	}
+}
+
+// chunkReader returns the chunks one at a time, counting the chunks read.
+type chunkReader struct {
+	chunks []string
+	read   int
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.read == len(r.chunks) {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[r.read])
+	r.read++
+	return n, nil
+}
+
+func TestParseStreamEmitsEarly(t *testing.T) {
+	filler := `<p>` + strings.Repeat("filler ", 2000) + `</p>`
+	r := &chunkReader{chunks: []string{
+		`<div itemscope><span itemprop="name">First</span></div>`,
+		filler, filler, filler, filler,
+		`<div itemscope><span itemprop="name">Second</span></div>`,
+	}}
+
+	var read []int
+	_, err := ParseStream(r, "text/html; charset=utf-8", nil, func(item *Item) error {
+		read = append(read, r.read)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first item is emitted before the filler is read, give or take the
+	// buffering of the UTF-8 conversion.
+	if len(read) != 2 || read[0] > 2 || read[1] != len(r.chunks) {
+		t.Errorf("Items should have been emitted as soon as they were read, but they were after %v chunks", read)
+	}
+}
+
+func TestParseStreamStops(t *testing.T) {
+	html := `<div itemscope></div><div itemscope></div><div itemscope></div>`
+	stop := errors.New("stop")
+
+	calls := 0
+	_, err := ParseStream(strings.NewReader(html), "text/html", nil, func(item *Item) error {
+		calls++
+		return stop
+	})
+	if err != stop {
+		t.Errorf("Result should have been the error of fn, but it was %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("fn should have been called once, but it was called %d times", calls)
+	}
+}
+
+func TestParseStreamPositions(t *testing.T) {
+	html := "<ul>\n<li itemscope><b itemprop=\"name\">é</b>\n<li itemscope>\n</ul>"
+
+	items, _ := parseStreamItems(html, t, WithPositions())
+	if len(items) != 2 {
+		t.Fatalf("Result should have had 2 items, but it had %d", len(items))
+	}
+
+	first, second := items[0].Position, items[1].Position
+	name := items[0].Properties["name"][0].Position
+	if result := html[first.Start:first.End]; result != "<li itemscope><b itemprop=\"name\">é</b>\n" {
+		t.Errorf("Result should have been the first li, but it was \"%s\"", result)
+	}
+	if result := html[second.Start:second.End]; result != "<li itemscope>\n" {
+		t.Errorf("Result should have been the second li, but it was \"%s\"", result)
+	}
+	if name.Line != 2 || name.Column != 15 || name.EndLine != 2 || name.EndColumn != 39 {
+		t.Errorf("Result should have been at 2:15-2:39, but it was at %d:%d-%d:%d", name.Line, name.Column, name.EndLine, name.EndColumn)
+	}
+}
+
+func TestParseStreamLimits(t *testing.T) {
+	html := `<div itemscope><p itemprop="name">First</p></div><div itemscope></div><div itemscope></div>`
+
+	items, diagnostics := parseStreamItems(html, t, WithLimits(Limits{MaxItems: 1, MaxValueLength: 3}))
+	if len(items) != 1 || items[0].GetString("name") != "Fir" {
+		t.Errorf("Result should have had the first item, truncated, but it had %v", items)
+	}
+	if len(diagnostics) != 2 || diagnostics[0].Code != DiagLimitExceeded || diagnostics[1].Code != DiagLimitExceeded {
+		t.Errorf("Result should have had 2 limit diagnostics, but it had \"%v\"", diagnostics)
+	}
+}
+
+func TestParseStreamForgetsIdentified(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<div itemscope itemref="kept late"></div><p id="kept" itemprop="kept">K</p>`)
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&b, `<p id="f%d" itemprop="filler">%d</p>`, i, i)
+	}
+	b.WriteString(`<p id="late" itemprop="late">L</p><div itemscope itemref="f0 f99"></div>`)
+
+	var items []*Item
+	s := newStreamParser(nil, func(item *Item) error {
+		items = append(items, item)
+		return nil
+	})
+	s.maxIdentified = 2
+	if err := s.run(strings.NewReader(b.String())); err != nil {
+		t.Fatal(err)
+	}
+
+	// The element referenced by the buffered item is kept, the oldest
+	// others are forgotten.
+	if len(items) != 2 || items[0].GetString("kept") != "K" || items[0].GetString("late") != "L" {
+		t.Errorf("Result should have had the buffered item with both references, but it had %v", items)
+	} else if filler := stringValues(items[1], "filler"); !reflect.DeepEqual(filler, []string{"99"}) {
+		t.Errorf("Result should have had the most recent filler, but it had %v", filler)
+	}
+	if d := s.data.Diagnostics; len(d) != 1 || d[0].Code != DiagUnresolvedItemref {
+		t.Errorf("Result should have had an unresolved itemref, but it had \"%v\"", d)
+	}
+	if len(s.identifiedNodes) != 2 || len(s.spans) != 2 || len(s.treeOrder) != 2 {
+		t.Errorf("Parser should have kept 2 elements, but it kept %d ids, %d spans and %d orders", len(s.identifiedNodes), len(s.spans), len(s.treeOrder))
+	}
+}
+
+func TestParseStreamWith(t *testing.T) {
+	html := "<div itemscope><a itemprop=\"url\" href=\"caf\xe9\">Caf\xe9</a></div>"
+
+	u, _ := url.Parse("http://example.com/shop/")
+	var items []*Item
+	fn := func(item *Item) error {
+		items = append(items, item)
+		return nil
+	}
+	if _, err := ParseStreamWith(strings.NewReader(html), fn, WithBaseURL(u), WithContentType("text/html; charset=iso-8859-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The options take precedence over the arguments of ParseStream.
+	if _, err := ParseStream(strings.NewReader(html), "text/html", nil, fn, WithBaseURL(u), WithContentType("text/html; charset=iso-8859-1")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, item := range items {
+		if result := item.GetString("url"); result != "http://example.com/shop/caf%C3%A9" {
+			t.Errorf("Result should have been \"http://example.com/shop/caf%%C3%%A9\", but it was \"%s\"", result)
+		}
+	}
+	if len(items) != 2 {
+		t.Errorf("Result should have had 2 items, but it had %d", len(items))
+	}
+}
+
+func TestParseStreamReleasesDiagnostics(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		b.WriteString(`<p id="dup"></p>`)
+	}
+	html := b.String()
+
+	_, diagnostics := parseStreamItems(html, t)
+	if len(diagnostics) != 99 {
+		t.Fatalf("Result should have had 99 diagnostics, but it had %d", len(diagnostics))
+	}
+	for _, d := range diagnostics {
+		if d.Node != nil || d.Element != "p" || d.Line != 1 {
+			t.Errorf("Result should have been a located diagnostic without node, but it was %+v", d)
+			break
+		}
+	}
+
+	// With a handler, the diagnostics are not kept.
+	reported := 0
+	s := newStreamParser(nil, func(*Item) error { return nil })
+	s.configure(newParseConfig([]ParseOption{WithDiagnostics(func(d Diagnostic) {
+		if d.Node == nil {
+			t.Error("Reported diagnostic should have had its node")
+		}
+		reported++
+	})}))
+	if err := s.run(strings.NewReader(html)); err != nil {
+		t.Fatal(err)
+	}
+	if reported != 99 || len(s.data.Diagnostics) != 0 {
+		t.Errorf("Result should have had 99 reported diagnostics and none kept, but it had %d and %d", reported, len(s.data.Diagnostics))
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// asciiWhitespace holds the ASCII whitespace characters of the WHATWG
+// Infra standard, which separate the tokens of attribute values.
+const asciiWhitespace = "\t\n\f\r "
+
+// tokens returns the tokens of the given attribute value. The lenient parser
+// splits the value on spaces; the strict parser splits it on ASCII whitespace
+// and drops duplicate tokens, as the value is an unordered set of unique
+// space-separated tokens.
+func (p *parser) tokens(s string) []string {
+	if !p.strict {
+		var result []string
+		for _, token := range strings.Split(s, " ") {
+			if len(token) > 0 {
+				result = append(result, token)
+			}
+		}
+		return result
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, token := range strings.FieldsFunc(s, isASCIIWhitespace) {
+		if !seen[token] {
+			seen[token] = true
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// isASCIIWhitespace reports whether r is an ASCII whitespace character.
+func isASCIIWhitespace(r rune) bool {
+	return r < 0x80 && strings.ContainsRune(asciiWhitespace, r)
+}
+
+// readTypes adds the item types of the given itemtype value to the item. In
+// strict mode, types that are not absolute URLs are dropped, and types of
+// different vocabularies are reported.
+func (p *parser) readTypes(item *Item, node *html.Node, s string) {
+	for _, itemtype := range p.tokens(s) {
+		if p.strict && !isAbsoluteIRI(itemtype) {
+			p.warn(DiagRelativeItemtype, node, fmt.Sprintf("itemtype %q is not an absolute URL; type dropped", itemtype))
+			continue
+		}
+		item.addType(itemtype)
+	}
+
+	if p.strict && len(item.Types) > 1 {
+		vocab := vocabulary(item.Types[0])
+		for _, t := range item.Types[1:] {
+			if vocabulary(t) != vocab {
+				p.warn(DiagMixedVocabularies, node, fmt.Sprintf("itemtype %q is not in the vocabulary %q of the first type", t, vocab))
+			}
+		}
+	}
+}
+
+// validPropertyName reports whether the given property name is valid in
+// strict mode: an absolute URL, or a name without "." and ":" characters.
+func (p *parser) validPropertyName(node *html.Node, name string) bool {
+	if !p.strict || !strings.ContainsAny(name, ".:") || isAbsoluteIRI(name) {
+		return true
+	}
+	p.warn(DiagInvalidPropertyName, node, fmt.Sprintf("property name %q contains \".\" or \":\" but is not an absolute URL; property skipped", name))
+	return false
+}
@@ -0,0 +1,109 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const strictHTML = "<div itemscope itemtype=\"http://schema.org/Product\thttp://schema.org/Product Thing http://example.com/vocab#Gadget\" itemref=\"extra\n\">" +
+	"<span itemprop=\"name\tname\">Widget</span>" +
+	"<span itemprop=\"sku\" content=\"W-1\">W-1 (text)</span>" +
+	"<object itemprop=\"manual\" data=\" manual.pdf \">Manual</object>" +
+	"<time itemprop=\"releaseDate\">2015-07-19</time>" +
+	"<span itemprop=\"product.name http://schema.org/alternateName\">Thingamajig</span>" +
+	"<meta itemprop=\"description\" content=\"\">" +
+	"</div>" +
+	"<p id=\"extra\" itemprop=\"color\">Red</p>"
+
+func parseMode(html string, strict bool, t *testing.T) *Microdata {
+	u, _ := url.Parse("http://example.com/")
+	opts := []ParseOption{WithBaseURL(u)}
+	if strict {
+		opts = append(opts, WithStrictMode())
+	}
+	data, err := Parse(strings.NewReader(html), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseLenient(t *testing.T) {
+	data := parseMode(strictHTML, false, t)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tabs and newlines are part of the tokens, so the first type, the name
+	// and the itemref do not match.
+	expected := `{"items":[{"type":["http://schema.org/Product\thttp://schema.org/Product","Thing","http://example.com/vocab#Gadget"],"properties":{"http://schema.org/alternateName":["Thingamajig"],"manual":["Manual"],"name\tname":["Widget"],"product.name":["Thingamajig"],"sku":["W-1"]}}]}`
+	if result := string(b); result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	data := parseMode(strictHTML, true, t)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"items":[{"type":["http://schema.org/Product","http://example.com/vocab#Gadget"],"properties":{"color":["Red"],"description":[""],"http://schema.org/alternateName":["Thingamajig"],"manual":["http://example.com/manual.pdf"],"name":["Widget"],"releaseDate":["2015-07-19"],"sku":["W-1 (text)"]}}]}`
+	if result := string(b); result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+
+	item := data.Items[0]
+	if kind := item.Properties["manual"][0].Kind; kind != URLValue {
+		t.Errorf("Result should have been a URL value, but it was %s", kind)
+	}
+	if kind := item.Properties["releaseDate"][0].Kind; kind != DateTimeValue {
+		t.Errorf("Result should have been a datetime value, but it was %s", kind)
+	}
+
+	var codes []string
+	for _, d := range data.Diagnostics {
+		codes = append(codes, d.Code)
+	}
+	expectedCodes := []string{
+		DiagRelativeItemtype,
+		DiagMixedVocabularies,
+		DiagIgnoredContent,
+		DiagInvalidPropertyName,
+	}
+	if !reflect.DeepEqual(codes, expectedCodes) {
+		t.Errorf("Diagnostics should have been \"%v\", but they were \"%v\"", expectedCodes, codes)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	lenient := &parser{}
+	strict := &parser{strict: true}
+
+	tests := []struct {
+		p        *parser
+		s        string
+		expected []string
+	}{
+		{lenient, " a  b\tc ", []string{"a", "b\tc"}},
+		{lenient, "a a", []string{"a", "a"}},
+		{strict, " a  b\tc\n\fd\re ", []string{"a", "b", "c", "d", "e"}},
+		{strict, "a b a", []string{"a", "b"}},
+		// A no-break space is not ASCII whitespace.
+		{strict, "a b", []string{"a b"}},
+		{strict, "", nil},
+	}
+	for _, test := range tests {
+		if result := test.p.tokens(test.s); !reflect.DeepEqual(result, test.expected) {
+			t.Errorf("Result for %q should have been %q, but it was %q", test.s, test.expected, result)
+		}
+	}
+}
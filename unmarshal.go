@@ -0,0 +1,466 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Unmarshal stores the properties of the item in the struct pointed to by v,
+// much like encoding/json does for JSON objects.
+//
+// Struct fields are matched to property names using the "microdata" struct
+// tag, falling back to the field name. A property matches a field name
+// exactly or, failing that, case-insensitively. The tag "-" skips a field.
+//
+//	type Product struct {
+//		Type   []string `microdata:"http://schema.org/Product,itemtype"`
+//		ID     string   `microdata:",itemid"`
+//		Name   string   `microdata:"name"`
+//		Offers []Offer  `microdata:"offers"`
+//	}
+//
+// A field with the ",itemtype" option receives the item types; when the tag
+// names a type, Unmarshal returns an *ItemTypeError unless the item has that
+// type, the http and https schema.org namespaces being treated as the same.
+// A field with the ",itemid" option receives the item id. The option
+// "omitempty" is accepted for symmetry with Marshal and ignored.
+//
+// A slice field receives all values of a property, any other field the first
+// value. Struct fields receive nested items, time.Time fields are parsed with
+// Value.AsTime, time.Duration fields from ISO 8601 durations, numeric and
+// boolean fields are parsed from the string value, and fields implementing
+// encoding.TextUnmarshaler decode the string value themselves. Fields of type
+// *Item, Value, *Value and ValueList receive the values unconverted.
+//
+// When a value cannot be stored in a field, or a nested item lacks the type
+// required by the struct, Unmarshal skips the value, carries on and returns
+// an *UnmarshalTypeError or *ItemTypeError describing the earliest such error.
+func Unmarshal(item *Item, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	if item == nil {
+		return &InvalidUnmarshalError{reflect.TypeOf(item)}
+	}
+
+	d := &decoder{}
+	if err := d.item(item, rv.Elem(), ""); err != nil {
+		return err
+	}
+	return d.err
+}
+
+// InvalidUnmarshalError describes an invalid argument passed to Unmarshal.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "microdata: Unmarshal(nil)"
+	}
+	if e.Type == reflect.TypeOf((*Item)(nil)) {
+		return "microdata: Unmarshal(nil *Item)"
+	}
+	if e.Type.Kind() != reflect.Ptr {
+		return "microdata: Unmarshal(non-pointer " + e.Type.String() + ")"
+	}
+	return "microdata: Unmarshal(" + e.Type.String() + ")"
+}
+
+// UnmarshalTypeError describes a property value that cannot be stored in a
+// value of a specific Go type.
+type UnmarshalTypeError struct {
+	Property string // dotted path of the property
+	Value    *Value
+	Type     reflect.Type
+	Err      error // parse error, if any
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	s := fmt.Sprintf("microdata: cannot unmarshal %s value of property %q into Go value of type %s", e.Value.Kind, e.Property, e.Type)
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *UnmarshalTypeError) Unwrap() error {
+	return e.Err
+}
+
+// ItemTypeError is returned by Unmarshal when an item does not have the item
+// type required by the struct it is stored in.
+type ItemTypeError struct {
+	Property string // dotted path of the property holding the item
+	Want     string
+	Types    []string
+}
+
+func (e *ItemTypeError) Error() string {
+	if e.Property == "" {
+		return fmt.Sprintf("microdata: item of type %v is not a %s", e.Types, e.Want)
+	}
+	return fmt.Sprintf("microdata: item of type %v in property %q is not a %s", e.Types, e.Property, e.Want)
+}
+
+var (
+	itemType            = reflect.TypeOf(Item{})
+	valueType           = reflect.TypeOf(Value{})
+	valueListType       = reflect.TypeOf(ValueList{})
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// field describes a struct field mapped to a property.
+type field struct {
+	name      string
+	index     []int
+	typ       reflect.Type
+	omitEmpty bool
+	itemType  bool
+	itemID    bool
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedFields returns the fields of the given struct type that map to item
+// properties, types or ids.
+func cachedFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t, nil))
+	return f.([]field)
+}
+
+// typeFields returns the fields of the given struct type. Fields of embedded
+// structs without a tag name are promoted, as with encoding/json.
+func typeFields(t reflect.Type, index []int) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("microdata")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, typeFields(sf.Type, fieldIndex)...)
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		f := field{
+			name:      name,
+			index:     fieldIndex,
+			typ:       sf.Type,
+			omitEmpty: opts.contains("omitempty"),
+			itemType:  opts.contains("itemtype"),
+			itemID:    opts.contains("itemid"),
+		}
+		if f.name == "" && !f.itemType {
+			f.name = sf.Name
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+type tagOptions string
+
+// parseTag splits a struct field's microdata tag into its name and options.
+func parseTag(tag string) (string, tagOptions) {
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		return tag[:i], tagOptions(tag[i+1:])
+	}
+	return tag, ""
+}
+
+// contains reports whether the comma-separated list of options contains the
+// given option.
+func (o tagOptions) contains(option string) bool {
+	for _, s := range strings.Split(string(o), ",") {
+		if s == option {
+			return true
+		}
+	}
+	return false
+}
+
+type decoder struct {
+	// err holds the earliest error of a value that could not be stored.
+	err error
+}
+
+// saveError records the error unless an earlier one was recorded.
+func (d *decoder) saveError(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// item stores the given item in the given struct value. It returns an
+// *ItemTypeError, leaving the struct untouched, when the item does not have
+// the type required by the struct.
+func (d *decoder) item(item *Item, rv reflect.Value, path string) error {
+	fields := cachedFields(rv.Type())
+
+	for _, f := range fields {
+		if f.itemType && f.name != "" && !item.HasType(f.name) {
+			return &ItemTypeError{Property: path, Want: f.name, Types: item.Types}
+		}
+	}
+
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		switch {
+		case f.itemType:
+			switch {
+			case fv.Kind() == reflect.String && len(item.Types) > 0:
+				fv.SetString(item.Types[0])
+			case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+				types := reflect.MakeSlice(fv.Type(), len(item.Types), len(item.Types))
+				for i, t := range item.Types {
+					types.Index(i).SetString(t)
+				}
+				fv.Set(types)
+			}
+		case f.itemID:
+			if fv.Kind() == reflect.String {
+				fv.SetString(item.ID)
+			}
+		default:
+			name := item.propertyName(f.name)
+			values, ok := item.Properties[name]
+			if !ok {
+				continue
+			}
+			if err := d.values(values, fv, joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// propertyName returns the name of the property matching the given field
+// name, preferring an exact match over a case-insensitive one.
+func (i *Item) propertyName(name string) string {
+	if _, ok := i.Properties[name]; ok {
+		return name
+	}
+	for _, property := range i.propertyNames() {
+		if strings.EqualFold(property, name) {
+			return property
+		}
+	}
+	return name
+}
+
+// joinPath appends the property name to the dotted path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// values stores the given values in the given field value.
+func (d *decoder) values(values ValueList, rv reflect.Value, path string) error {
+	if rv.Type() == valueListType {
+		rv.Set(reflect.ValueOf(values))
+		return nil
+	}
+
+	if rv.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(rv.Type(), 0, len(values))
+		for _, value := range values {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			ok, err := d.value(value, elem, path)
+			if err != nil {
+				return err
+			}
+			if ok {
+				slice = reflect.Append(slice, elem)
+			}
+		}
+		rv.Set(slice)
+		return nil
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+	_, err := d.value(values[0], rv, path)
+	return err
+}
+
+// value stores the given value in the given Go value. It reports whether the
+// value was stored.
+func (d *decoder) value(value *Value, rv reflect.Value, path string) (bool, error) {
+	typeError := func(err error) (bool, error) {
+		d.saveError(&UnmarshalTypeError{Property: path, Value: value, Type: rv.Type(), Err: err})
+		return false, nil
+	}
+
+	switch rv.Type() {
+	case valueType:
+		rv.Set(reflect.ValueOf(*value))
+		return true, nil
+	case reflect.PtrTo(valueType):
+		rv.Set(reflect.ValueOf(value))
+		return true, nil
+	case reflect.PtrTo(itemType):
+		if value.Kind != ItemValue {
+			return typeError(nil)
+		}
+		rv.Set(reflect.ValueOf(value.Item))
+		return true, nil
+	}
+
+	if rv.Kind() == reflect.Ptr {
+		elem := reflect.New(rv.Type().Elem())
+		ok, err := d.value(value, elem.Elem(), path)
+		if ok {
+			rv.Set(elem)
+		}
+		return ok, err
+	}
+
+	if rv.Kind() == reflect.Interface && rv.NumMethod() == 0 {
+		if value.Kind == ItemValue {
+			rv.Set(reflect.ValueOf(value.Item))
+		} else {
+			rv.Set(reflect.ValueOf(value.Raw))
+		}
+		return true, nil
+	}
+
+	if value.Kind == ItemValue {
+		if rv.Kind() != reflect.Struct || rv.Type() == timeType || rv.Type() == urlType {
+			return typeError(nil)
+		}
+		if err := d.item(value.Item, rv, path); err != nil {
+			d.saveError(err)
+			return false, nil
+		}
+		return true, nil
+	}
+
+	switch rv.Type() {
+	case timeType:
+		t, err := value.AsTime()
+		if err != nil {
+			return typeError(err)
+		}
+		rv.Set(reflect.ValueOf(t))
+		return true, nil
+	case durationType:
+		dur, err := parseDuration(value.Raw)
+		if err != nil {
+			return typeError(err)
+		}
+		rv.SetInt(int64(dur))
+		return true, nil
+	case urlType:
+		u, err := value.AsURL()
+		if err != nil {
+			return typeError(err)
+		}
+		rv.Set(reflect.ValueOf(*u))
+		return true, nil
+	}
+
+	if rv.CanAddr() && rv.Addr().Type().Implements(textUnmarshalerType) {
+		if err := rv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value.Raw)); err != nil {
+			return typeError(err)
+		}
+		return true, nil
+	}
+
+	s := strings.TrimSpace(value.Raw)
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(value.Raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return typeError(err)
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return typeError(err)
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return typeError(err)
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return typeError(err)
+		}
+		rv.SetFloat(n)
+	default:
+		return typeError(nil)
+	}
+	return true, nil
+}
+
+// durationPattern matches ISO 8601 durations such as "PT1H30M" or "P1DT2H".
+// Years and months are not supported, as their length varies.
+var durationPattern = regexp.MustCompile(`^P(?:(\d+(?:[.,]\d+)?)W)?(?:(\d+(?:[.,]\d+)?)D)?(?:T(?:(\d+(?:[.,]\d+)?)H)?(?:(\d+(?:[.,]\d+)?)M)?(?:(\d+(?:[.,]\d+)?)S)?)?$`)
+
+// parseDuration parses an ISO 8601 duration. A leading "-", as in XML Schema
+// durations, makes the duration negative.
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	p := strings.TrimPrefix(s, "-")
+	m := durationPattern.FindStringSubmatch(p)
+	if m == nil || p == "P" || strings.HasSuffix(p, "T") {
+		return 0, fmt.Errorf("microdata: invalid ISO 8601 duration %q", s)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.Replace(m[i+1], ",", ".", 1), 64)
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n * float64(unit))
+	}
+	if len(p) < len(s) {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRecipe struct {
+	Type         []string      `microdata:"http://schema.org/Recipe,itemtype"`
+	ID           string        `microdata:",itemid"`
+	Name         string        `microdata:"name"`
+	Published    time.Time     `microdata:"datePublished"`
+	CookTime     time.Duration `microdata:"cookTime"`
+	Servings     int           `microdata:"recipeYield"`
+	Instructions []string      `microdata:"recipeInstructions"`
+	Rating       *testRating   `microdata:"aggregateRating,omitempty"`
+	Author       testPerson    `microdata:"author"`
+	Keywords     ValueList     `microdata:"keywords"`
+	Image        *Value
+	Ignored      string `microdata:"-"`
+}
+
+type testRating struct {
+	Value float64 `microdata:"ratingValue"`
+	Count uint    `microdata:"ratingCount"`
+}
+
+type testPerson struct {
+	Type string `microdata:"http://schema.org/Person,itemtype"`
+	Name string
+}
+
+var recipeSnippet = `
+<div itemscope itemtype="http://schema.org/Recipe" itemid="http://example.com/pancakes">
+	<h1 itemprop="name">Pancakes</h1>
+	<img itemprop="image" src="pancakes.jpg" />
+	<time itemprop="datePublished" datetime="2015-07-19">July 19</time>
+	<meta itemprop="cookTime" content="PT1H30M" />
+	<meta itemprop="keywords" content="breakfast" />
+	<meta itemprop="keywords" content="sweet" />
+	<data itemprop="recipeYield" value="4">four servings</data>
+	<ol>
+		<li itemprop="recipeInstructions">Mix.</li>
+		<li itemprop="recipeInstructions">Fry.</li>
+	</ol>
+	<div itemprop="aggregateRating" itemscope itemtype="http://schema.org/AggregateRating">
+		<meter itemprop="ratingValue" min="0" max="5" value="4.5">4.5</meter>
+		<data itemprop="ratingCount" value="12">12 ratings</data>
+	</div>
+	<div itemprop="author" itemscope itemtype="http://schema.org/Person">
+		<span itemprop="name">Penelope</span>
+	</div>
+	<span itemprop="ignored">Ignored</span>
+</div>`
+
+func TestUnmarshal(t *testing.T) {
+	item := ParseData(recipeSnippet, t).Items[0]
+
+	var recipe testRecipe
+	if err := Unmarshal(item, &recipe); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := testRecipe{
+		Type:         []string{"http://schema.org/Recipe"},
+		ID:           "http://example.com/pancakes",
+		Name:         "Pancakes",
+		Published:    time.Date(2015, 7, 19, 0, 0, 0, 0, time.UTC),
+		CookTime:     90 * time.Minute,
+		Servings:     4,
+		Instructions: []string{"Mix.", "Fry."},
+		Rating:       &testRating{Value: 4.5, Count: 12},
+		Author:       testPerson{Type: "http://schema.org/Person", Name: "Penelope"},
+		Keywords:     item.Properties["keywords"],
+		Image:        item.Properties["image"][0],
+	}
+	if !reflect.DeepEqual(recipe, expected) {
+		t.Errorf("Result should have been \"%+v\", but it was \"%+v\"", expected, recipe)
+	}
+}
+
+func TestUnmarshalItemTypeError(t *testing.T) {
+	item := ParseData(productSnippet, t).Items[0]
+
+	var recipe testRecipe
+	err := Unmarshal(item, &recipe)
+
+	var typeErr *ItemTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Error should have been an *ItemTypeError, but it was \"%v\"", err)
+	}
+	if typeErr.Want != "http://schema.org/Recipe" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "http://schema.org/Recipe", typeErr.Want)
+	}
+}
+
+func TestUnmarshalSchemaOrgScheme(t *testing.T) {
+	html := `
+		<div itemscope itemtype="https://schema.org/Recipe">
+			<span itemprop="name">Pancakes</span>
+		</div>`
+
+	item := ParseData(html, t).Items[0]
+
+	var recipe testRecipe
+	if err := Unmarshal(item, &recipe); err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Name != "Pancakes" {
+		t.Errorf("Result should have been \"Pancakes\", but it was \"%s\"", recipe.Name)
+	}
+
+	var other struct {
+		Type []string `microdata:"https://example.com/Recipe,itemtype"`
+	}
+	if err := Unmarshal(item, &other); err == nil {
+		t.Error("Error should have been returned for a type of another vocabulary")
+	}
+}
+
+func TestUnmarshalTypeError(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://schema.org/Recipe">
+			<span itemprop="name">Pancakes</span>
+			<span itemprop="recipeYield">four</span>
+		</div>`
+
+	item := ParseData(html, t).Items[0]
+
+	var recipe testRecipe
+	err := Unmarshal(item, &recipe)
+
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Error should have been an *UnmarshalTypeError, but it was \"%v\"", err)
+	}
+	if typeErr.Property != "recipeYield" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "recipeYield", typeErr.Property)
+	}
+	if recipe.Name != "Pancakes" {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", "Pancakes", recipe.Name)
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	var recipe testRecipe
+	for _, v := range []interface{}{nil, recipe, (*testRecipe)(nil), new(string)} {
+		var invalidErr *InvalidUnmarshalError
+		if err := Unmarshal(NewItem(), v); !errors.As(err, &invalidErr) {
+			t.Errorf("Error should have been an *InvalidUnmarshalError, but it was \"%v\"", err)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	var testTable = []struct {
+		s        string
+		expected time.Duration
+	}{
+		{"PT30M", 30 * time.Minute},
+		{"PT1H30M", 90 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"PT0.5S", 500 * time.Millisecond},
+		{"-PT1M30S", -90 * time.Second},
+	}
+
+	for _, test := range testTable {
+		result, err := parseDuration(test.s)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+
+	for _, s := range []string{"", "P", "PT", "-P", "--PT1S", "30M", "P1Y"} {
+		if _, err := parseDuration(s); err == nil {
+			t.Errorf("Error should have been returned for \"%s\"", s)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/damian-szulc/microdata"
+)
+
+// Profile finding codes.
+const (
+	// MissingRequired reports a required property without value.
+	MissingRequired = "missing-required"
+	// MissingRecommended reports a recommended property without value.
+	MissingRecommended = "missing-recommended"
+	// InvalidFormat reports a value that is not in the expected format.
+	InvalidFormat = "invalid-format"
+)
+
+// Format checks the format of a value which is not an item.
+type Format func(value *microdata.Value) error
+
+// Requirement is a property an item must, or should, have. The requirement
+// is met when the property at Path, or at any of the AnyOf paths, has a
+// value. Paths are dotted paths as accepted by Item.GetAll, e.g.
+// "offers.price", and are met by a single value among all the nested items.
+type Requirement struct {
+	Path  string
+	AnyOf []string
+	// Format, when set, checks each value of the property that is not an
+	// item. Recommended properties are checked as well.
+	Format Format
+}
+
+// paths returns the paths of the requirement.
+func (r Requirement) paths() []string {
+	if r.Path == "" {
+		return r.AnyOf
+	}
+	return append([]string{r.Path}, r.AnyOf...)
+}
+
+// Profile describes the properties an item of a schema.org type needs to
+// qualify for a rich result. Profiles are plain values; callers may add
+// their own or extend the default ones.
+type Profile struct {
+	// Type is the schema.org type of the items the profile applies to, e.g.
+	// "Product". Items of subtypes are not matched.
+	Type        string
+	Required    []Requirement
+	Recommended []Requirement
+}
+
+var (
+	// durationPattern matches ISO 8601 durations, e.g. "PT1H30M".
+	durationPattern = regexp.MustCompile(`^P(?:\d+Y)?(?:\d+M)?(?:\d+W)?(?:\d+D)?(?:T(?:\d+H)?(?:\d+M)?(?:\d+(?:\.\d+)?S)?)?$`)
+	// currencyPattern matches ISO 4217 currency codes, e.g. "EUR".
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// The formats of the default profiles.
+var (
+	// Duration accepts ISO 8601 durations, e.g. "PT1H30M".
+	Duration Format = func(value *microdata.Value) error {
+		s := strings.TrimSpace(value.Raw)
+		if s == "P" || strings.HasSuffix(s, "T") || !durationPattern.MatchString(s) {
+			return fmt.Errorf("%q is not an ISO 8601 duration", value.Raw)
+		}
+		return nil
+	}
+	// Date accepts ISO 8601 dates, and dates and times.
+	Date Format = func(value *microdata.Value) error {
+		if _, err := value.AsTime(); err != nil {
+			return fmt.Errorf("%q is not an ISO 8601 date", value.Raw)
+		}
+		return nil
+	}
+	// CurrencyCode accepts ISO 4217 currency codes, e.g. "USD".
+	CurrencyCode Format = func(value *microdata.Value) error {
+		if !currencyPattern.MatchString(strings.TrimSpace(value.Raw)) {
+			return fmt.Errorf("%q is not an ISO 4217 currency code", value.Raw)
+		}
+		return nil
+	}
+	// URL accepts absolute http and https URLs.
+	URL Format = func(value *microdata.Value) error {
+		u, err := url.Parse(strings.TrimSpace(value.Raw))
+		if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("%q is not an absolute URL", value.Raw)
+		}
+		return nil
+	}
+	// Number accepts numbers, e.g. "4.5".
+	Number Format = func(value *microdata.Value) error {
+		if _, err := value.AsFloat(); err != nil {
+			return fmt.Errorf("%q is not a number", value.Raw)
+		}
+		return nil
+	}
+)
+
+// The default profiles, after the requirements of the Google rich results.
+var (
+	ProductProfile = &Profile{
+		Type: "Product",
+		Required: []Requirement{
+			{Path: "name"},
+			{AnyOf: []string{"offers", "review", "aggregateRating"}},
+		},
+		Recommended: []Requirement{
+			{Path: "image", Format: URL},
+			{Path: "description"},
+			{Path: "sku"},
+			{Path: "brand"},
+			{Path: "offers.price", Format: Number},
+			{Path: "offers.priceCurrency", Format: CurrencyCode},
+			{Path: "offers.availability"},
+			{Path: "aggregateRating.ratingValue", Format: Number},
+		},
+	}
+
+	RecipeProfile = &Profile{
+		Type: "Recipe",
+		Required: []Requirement{
+			{Path: "name"},
+			{Path: "image", Format: URL},
+		},
+		Recommended: []Requirement{
+			{Path: "author"},
+			{Path: "datePublished", Format: Date},
+			{Path: "description"},
+			{Path: "prepTime", Format: Duration},
+			{Path: "cookTime", Format: Duration},
+			{Path: "totalTime", Format: Duration},
+			{Path: "recipeYield"},
+			{Path: "recipeIngredient"},
+			{Path: "recipeInstructions"},
+			{Path: "recipeCategory"},
+			{Path: "recipeCuisine"},
+			{Path: "nutrition.calories"},
+			{Path: "aggregateRating.ratingValue", Format: Number},
+		},
+	}
+
+	EventProfile = &Profile{
+		Type: "Event",
+		Required: []Requirement{
+			{Path: "name"},
+			{Path: "startDate", Format: Date},
+			{Path: "location"},
+		},
+		Recommended: []Requirement{
+			{Path: "description"},
+			{Path: "endDate", Format: Date},
+			{Path: "eventStatus"},
+			{Path: "image", Format: URL},
+			{Path: "organizer"},
+			{Path: "performer"},
+			{Path: "offers.price", Format: Number},
+			{Path: "offers.priceCurrency", Format: CurrencyCode},
+			{Path: "offers.url", Format: URL},
+		},
+	}
+
+	JobPostingProfile = &Profile{
+		Type: "JobPosting",
+		Required: []Requirement{
+			{Path: "title"},
+			{Path: "description"},
+			{Path: "datePosted", Format: Date},
+			{Path: "hiringOrganization"},
+			{Path: "jobLocation"},
+		},
+		Recommended: []Requirement{
+			{Path: "validThrough", Format: Date},
+			{Path: "employmentType"},
+			{Path: "identifier"},
+			{Path: "baseSalary"},
+			{Path: "baseSalary.currency", Format: CurrencyCode},
+		},
+	}
+
+	BreadcrumbListProfile = &Profile{
+		Type: "BreadcrumbList",
+		Required: []Requirement{
+			{Path: "itemListElement"},
+			{Path: "itemListElement.position", Format: Number},
+			{AnyOf: []string{"itemListElement.name", "itemListElement.item.name"}},
+		},
+	}
+
+	// DefaultProfiles holds the default profiles, used by CheckRichResults.
+	DefaultProfiles = []*Profile{
+		ProductProfile,
+		RecipeProfile,
+		EventProfile,
+		JobPostingProfile,
+		BreadcrumbListProfile,
+	}
+)
+
+// CheckRichResults checks the items against the default profiles. See
+// CheckProfiles.
+func CheckRichResults(data *microdata.Microdata) []Finding {
+	return CheckProfiles(data, DefaultProfiles)
+}
+
+// CheckProfiles checks each of the items against the profiles of its types.
+// Items matching no profile are not reported. The paths of the findings
+// start with "items[i]".
+func CheckProfiles(data *microdata.Microdata, profiles []*Profile) []Finding {
+	var findings []Finding
+	for i, item := range data.Items {
+		for _, p := range profiles {
+			if !hasType(item, p.Type) {
+				continue
+			}
+			for _, f := range p.Check(item) {
+				f.Path = join(fmt.Sprintf("items[%d]", i), f.Path)
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings
+}
+
+// hasType reports whether the item has the given schema.org type.
+func hasType(item *microdata.Item, name string) bool {
+	for _, t := range item.Types {
+		if term, ok := schemaOrgTerm(t); ok && term == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Check checks the item against the profile, regardless of its types. The
+// paths of the findings are the paths of the requirements.
+func (p *Profile) Check(item *microdata.Item) []Finding {
+	var findings []Finding
+	check := func(requirements []Requirement, code, kind string) {
+		for _, r := range requirements {
+			paths := r.paths()
+			present := false
+			for _, path := range paths {
+				values := item.GetAll(path)
+				if len(values) > 0 {
+					present = true
+				}
+				if r.Format == nil {
+					continue
+				}
+				for j, value := range values {
+					if value.Kind == microdata.ItemValue {
+						continue
+					}
+					if err := r.Format(value); err != nil {
+						findings = append(findings, Finding{
+							Path:    fmt.Sprintf("%s[%d]", path, j),
+							Code:    InvalidFormat,
+							Message: fmt.Sprintf("property %q: %v", path, err),
+						})
+					}
+				}
+			}
+			if present {
+				continue
+			}
+
+			f := Finding{Path: paths[0], Code: code}
+			if len(paths) == 1 {
+				f.Message = fmt.Sprintf("missing %s property %q", kind, paths[0])
+			} else {
+				quoted := make([]string, len(paths))
+				for i, path := range paths {
+					quoted[i] = fmt.Sprintf("%q", path)
+				}
+				f.Message = fmt.Sprintf("missing %s property, one of %s", kind, strings.Join(quoted, ", "))
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	check(p.Required, MissingRequired, "required")
+	check(p.Recommended, MissingRecommended, "recommended")
+	return findings
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damian-szulc/microdata"
+)
+
+func TestCheckRichResults(t *testing.T) {
+	data := parse(`
+		<div itemscope itemtype="https://schema.org/Product">
+			<span itemprop="name">Anvil</span>
+			<img itemprop="image" src="http://example.com/anvil.jpg">
+			<span itemprop="description">A heavy anvil</span>
+			<span itemprop="sku">A-1</span>
+			<span itemprop="brand">ACME</span>
+			<div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+				<span itemprop="price">$119.99</span>
+				<meta itemprop="priceCurrency" content="usd">
+				<link itemprop="availability" href="https://schema.org/InStock">
+			</div>
+		</div>
+		<div itemscope itemtype="https://schema.org/Recipe">
+			<span itemprop="name">Pancakes</span>
+			<meta itemprop="cookTime" content="10 minutes">
+		</div>
+		<div itemscope itemtype="https://schema.org/Person">
+			<span itemprop="name">Not checked</span>
+		</div>`, t)
+
+	var result []string
+	for _, f := range CheckRichResults(data) {
+		result = append(result, f.String())
+	}
+	expected := []string{
+		`items[0].offers.price[0]: invalid-format: property "offers.price": "$119.99" is not a number`,
+		`items[0].offers.priceCurrency[0]: invalid-format: property "offers.priceCurrency": "usd" is not an ISO 4217 currency code`,
+		`items[0].aggregateRating.ratingValue: missing-recommended: missing recommended property "aggregateRating.ratingValue"`,
+		`items[1].image: missing-required: missing required property "image"`,
+		`items[1].author: missing-recommended: missing recommended property "author"`,
+		`items[1].datePublished: missing-recommended: missing recommended property "datePublished"`,
+		`items[1].description: missing-recommended: missing recommended property "description"`,
+		`items[1].prepTime: missing-recommended: missing recommended property "prepTime"`,
+		`items[1].cookTime[0]: invalid-format: property "cookTime": "10 minutes" is not an ISO 8601 duration`,
+		`items[1].totalTime: missing-recommended: missing recommended property "totalTime"`,
+		`items[1].recipeYield: missing-recommended: missing recommended property "recipeYield"`,
+		`items[1].recipeIngredient: missing-recommended: missing recommended property "recipeIngredient"`,
+		`items[1].recipeInstructions: missing-recommended: missing recommended property "recipeInstructions"`,
+		`items[1].recipeCategory: missing-recommended: missing recommended property "recipeCategory"`,
+		`items[1].recipeCuisine: missing-recommended: missing recommended property "recipeCuisine"`,
+		`items[1].nutrition.calories: missing-recommended: missing recommended property "nutrition.calories"`,
+		`items[1].aggregateRating.ratingValue: missing-recommended: missing recommended property "aggregateRating.ratingValue"`,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", strings.Join(expected, "\n"), strings.Join(result, "\n"))
+	}
+}
+
+func TestProfileAnyOf(t *testing.T) {
+	data := parse(`
+		<ol itemscope itemtype="https://schema.org/BreadcrumbList">
+			<li itemprop="itemListElement" itemscope itemtype="https://schema.org/ListItem">
+				<a itemprop="item" itemscope itemtype="https://schema.org/Thing" itemid="http://example.com/books">
+					<span itemprop="name">Books</span>
+				</a>
+				<meta itemprop="position" content="1">
+			</li>
+		</ol>
+		<div itemscope itemtype="https://schema.org/Product">
+			<span itemprop="name">Anvil</span>
+		</div>`, t)
+
+	findings := CheckProfiles(data, []*Profile{BreadcrumbListProfile, {
+		Type:     "Product",
+		Required: []Requirement{{AnyOf: []string{"offers", "review", "aggregateRating"}}},
+	}})
+
+	expected := []Finding{{
+		Path:    "items[1].offers",
+		Code:    MissingRequired,
+		Message: `missing required property, one of "offers", "review", "aggregateRating"`,
+	}}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", expected, findings)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	var testTable = []struct {
+		name   string
+		format Format
+		value  string
+		valid  bool
+	}{
+		{"Duration", Duration, "PT1H30M", true},
+		{"Duration", Duration, "P1DT", false},
+		{"Duration", Duration, "P", false},
+		{"Date", Date, "2015-07-19", true},
+		{"Date", Date, "2015-07-19T20:00:00Z", true},
+		{"Date", Date, "July 19", false},
+		{"CurrencyCode", CurrencyCode, "EUR", true},
+		{"CurrencyCode", CurrencyCode, "€", false},
+		{"URL", URL, "https://example.com/a.jpg", true},
+		{"URL", URL, "a.jpg", false},
+		{"Number", Number, "4.5", true},
+		{"Number", Number, "four", false},
+	}
+
+	for _, test := range testTable {
+		err := test.format(&microdata.Value{Kind: microdata.TextValue, Raw: test.value})
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("Result of %s(%q) should have been valid %v, but it was %v", test.name, test.value, test.valid, valid)
+		}
+	}
+}
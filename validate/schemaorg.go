@@ -0,0 +1,170 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package validate
+
+// schemaOrgSnapshot is a subset of the schema.org vocabulary, in the JSON-LD
+// format of the schema.org releases, covering the types and properties
+// commonly found in structured data. Use LoadVocabularyFile to validate
+// against a complete release, such as schemaorg-current-https.jsonld.
+const schemaOrgSnapshot = `{
+  "@context": {
+    "rdf": "http://www.w3.org/1999/02/22-rdf-syntax-ns#",
+    "rdfs": "http://www.w3.org/2000/01/rdf-schema#",
+    "schema": "https://schema.org/"
+  },
+  "@graph": [
+    {"@id": "schema:Thing", "@type": "rdfs:Class", "rdfs:label": "Thing"},
+    {"@id": "schema:DataType", "@type": "rdfs:Class", "rdfs:label": "DataType"},
+    {"@id": "schema:Text", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Text"},
+    {"@id": "schema:URL", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "URL", "rdfs:subClassOf": {"@id": "schema:Text"}},
+    {"@id": "schema:Number", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Number"},
+    {"@id": "schema:Integer", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Integer", "rdfs:subClassOf": {"@id": "schema:Number"}},
+    {"@id": "schema:Float", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Float", "rdfs:subClassOf": {"@id": "schema:Number"}},
+    {"@id": "schema:Boolean", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Boolean"},
+    {"@id": "schema:Date", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Date"},
+    {"@id": "schema:DateTime", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "DateTime"},
+    {"@id": "schema:Time", "@type": ["schema:DataType", "rdfs:Class"], "rdfs:label": "Time"},
+    {"@id": "schema:CreativeWork", "@type": "rdfs:Class", "rdfs:label": "CreativeWork", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:Article", "@type": "rdfs:Class", "rdfs:label": "Article", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:NewsArticle", "@type": "rdfs:Class", "rdfs:label": "NewsArticle", "rdfs:subClassOf": {"@id": "schema:Article"}},
+    {"@id": "schema:BlogPosting", "@type": "rdfs:Class", "rdfs:label": "BlogPosting", "rdfs:subClassOf": {"@id": "schema:Article"}},
+    {"@id": "schema:WebPage", "@type": "rdfs:Class", "rdfs:label": "WebPage", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:WebSite", "@type": "rdfs:Class", "rdfs:label": "WebSite", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:Recipe", "@type": "rdfs:Class", "rdfs:label": "Recipe", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:Review", "@type": "rdfs:Class", "rdfs:label": "Review", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:MediaObject", "@type": "rdfs:Class", "rdfs:label": "MediaObject", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:ImageObject", "@type": "rdfs:Class", "rdfs:label": "ImageObject", "rdfs:subClassOf": {"@id": "schema:MediaObject"}},
+    {"@id": "schema:VideoObject", "@type": "rdfs:Class", "rdfs:label": "VideoObject", "rdfs:subClassOf": {"@id": "schema:MediaObject"}},
+    {"@id": "schema:HowToStep", "@type": "rdfs:Class", "rdfs:label": "HowToStep", "rdfs:subClassOf": {"@id": "schema:CreativeWork"}},
+    {"@id": "schema:Person", "@type": "rdfs:Class", "rdfs:label": "Person", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:Organization", "@type": "rdfs:Class", "rdfs:label": "Organization", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:LocalBusiness", "@type": "rdfs:Class", "rdfs:label": "LocalBusiness", "rdfs:subClassOf": [{"@id": "schema:Organization"}, {"@id": "schema:Place"}]},
+    {"@id": "schema:Place", "@type": "rdfs:Class", "rdfs:label": "Place", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:Product", "@type": "rdfs:Class", "rdfs:label": "Product", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:Event", "@type": "rdfs:Class", "rdfs:label": "Event", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:MusicEvent", "@type": "rdfs:Class", "rdfs:label": "MusicEvent", "rdfs:subClassOf": {"@id": "schema:Event"}},
+    {"@id": "schema:Intangible", "@type": "rdfs:Class", "rdfs:label": "Intangible", "rdfs:subClassOf": {"@id": "schema:Thing"}},
+    {"@id": "schema:Brand", "@type": "rdfs:Class", "rdfs:label": "Brand", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:Offer", "@type": "rdfs:Class", "rdfs:label": "Offer", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:Demand", "@type": "rdfs:Class", "rdfs:label": "Demand", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:Rating", "@type": "rdfs:Class", "rdfs:label": "Rating", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:AggregateRating", "@type": "rdfs:Class", "rdfs:label": "AggregateRating", "rdfs:subClassOf": {"@id": "schema:Rating"}},
+    {"@id": "schema:JobPosting", "@type": "rdfs:Class", "rdfs:label": "JobPosting", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:ItemList", "@type": "rdfs:Class", "rdfs:label": "ItemList", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:BreadcrumbList", "@type": "rdfs:Class", "rdfs:label": "BreadcrumbList", "rdfs:subClassOf": {"@id": "schema:ItemList"}},
+    {"@id": "schema:ListItem", "@type": "rdfs:Class", "rdfs:label": "ListItem", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:StructuredValue", "@type": "rdfs:Class", "rdfs:label": "StructuredValue", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:ContactPoint", "@type": "rdfs:Class", "rdfs:label": "ContactPoint", "rdfs:subClassOf": {"@id": "schema:StructuredValue"}},
+    {"@id": "schema:PostalAddress", "@type": "rdfs:Class", "rdfs:label": "PostalAddress", "rdfs:subClassOf": {"@id": "schema:ContactPoint"}},
+    {"@id": "schema:GeoCoordinates", "@type": "rdfs:Class", "rdfs:label": "GeoCoordinates", "rdfs:subClassOf": {"@id": "schema:StructuredValue"}},
+    {"@id": "schema:MonetaryAmount", "@type": "rdfs:Class", "rdfs:label": "MonetaryAmount", "rdfs:subClassOf": {"@id": "schema:StructuredValue"}},
+    {"@id": "schema:QuantitativeValue", "@type": "rdfs:Class", "rdfs:label": "QuantitativeValue", "rdfs:subClassOf": {"@id": "schema:StructuredValue"}},
+    {"@id": "schema:NutritionInformation", "@type": "rdfs:Class", "rdfs:label": "NutritionInformation", "rdfs:subClassOf": {"@id": "schema:StructuredValue"}},
+    {"@id": "schema:PriceSpecification", "@type": "rdfs:Class", "rdfs:label": "PriceSpecification", "rdfs:subClassOf": {"@id": "schema:StructuredValue"}},
+    {"@id": "schema:Quantity", "@type": "rdfs:Class", "rdfs:label": "Quantity", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:Duration", "@type": "rdfs:Class", "rdfs:label": "Duration", "rdfs:subClassOf": {"@id": "schema:Quantity"}},
+    {"@id": "schema:Energy", "@type": "rdfs:Class", "rdfs:label": "Energy", "rdfs:subClassOf": {"@id": "schema:Quantity"}},
+    {"@id": "schema:Enumeration", "@type": "rdfs:Class", "rdfs:label": "Enumeration", "rdfs:subClassOf": {"@id": "schema:Intangible"}},
+    {"@id": "schema:ItemAvailability", "@type": "rdfs:Class", "rdfs:label": "ItemAvailability", "rdfs:subClassOf": {"@id": "schema:Enumeration"}},
+    {"@id": "schema:OfferItemCondition", "@type": "rdfs:Class", "rdfs:label": "OfferItemCondition", "rdfs:subClassOf": {"@id": "schema:Enumeration"}},
+    {"@id": "schema:EventStatusType", "@type": "rdfs:Class", "rdfs:label": "EventStatusType", "rdfs:subClassOf": {"@id": "schema:Enumeration"}},
+    {"@id": "schema:AdministrativeArea", "@type": "rdfs:Class", "rdfs:label": "AdministrativeArea", "rdfs:subClassOf": {"@id": "schema:Place"}},
+    {"@id": "schema:Country", "@type": "rdfs:Class", "rdfs:label": "Country", "rdfs:subClassOf": {"@id": "schema:AdministrativeArea"}},
+    {"@id": "schema:name", "@type": "rdf:Property", "rdfs:label": "name", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:alternateName", "@type": "rdf:Property", "rdfs:label": "alternateName", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:description", "@type": "rdf:Property", "rdfs:label": "description", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:url", "@type": "rdf:Property", "rdfs:label": "url", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": {"@id": "schema:URL"}},
+    {"@id": "schema:image", "@type": "rdf:Property", "rdfs:label": "image", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": [{"@id": "schema:ImageObject"}, {"@id": "schema:URL"}]},
+    {"@id": "schema:sameAs", "@type": "rdf:Property", "rdfs:label": "sameAs", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": {"@id": "schema:URL"}},
+    {"@id": "schema:identifier", "@type": "rdf:Property", "rdfs:label": "identifier", "schema:domainIncludes": {"@id": "schema:Thing"}, "schema:rangeIncludes": [{"@id": "schema:Text"}, {"@id": "schema:URL"}]},
+    {"@id": "schema:author", "@type": "rdf:Property", "rdfs:label": "author", "schema:domainIncludes": [{"@id": "schema:CreativeWork"}, {"@id": "schema:Rating"}], "schema:rangeIncludes": [{"@id": "schema:Organization"}, {"@id": "schema:Person"}]},
+    {"@id": "schema:publisher", "@type": "rdf:Property", "rdfs:label": "publisher", "schema:domainIncludes": {"@id": "schema:CreativeWork"}, "schema:rangeIncludes": [{"@id": "schema:Organization"}, {"@id": "schema:Person"}]},
+    {"@id": "schema:headline", "@type": "rdf:Property", "rdfs:label": "headline", "schema:domainIncludes": {"@id": "schema:CreativeWork"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:datePublished", "@type": "rdf:Property", "rdfs:label": "datePublished", "schema:domainIncludes": {"@id": "schema:CreativeWork"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:dateModified", "@type": "rdf:Property", "rdfs:label": "dateModified", "schema:domainIncludes": {"@id": "schema:CreativeWork"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:keywords", "@type": "rdf:Property", "rdfs:label": "keywords", "schema:domainIncludes": [{"@id": "schema:CreativeWork"}, {"@id": "schema:Organization"}, {"@id": "schema:Place"}, {"@id": "schema:Product"}, {"@id": "schema:Event"}], "schema:rangeIncludes": [{"@id": "schema:Text"}, {"@id": "schema:URL"}]},
+    {"@id": "schema:inLanguage", "@type": "rdf:Property", "rdfs:label": "inLanguage", "schema:domainIncludes": [{"@id": "schema:CreativeWork"}, {"@id": "schema:Event"}], "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:about", "@type": "rdf:Property", "rdfs:label": "about", "schema:domainIncludes": [{"@id": "schema:CreativeWork"}, {"@id": "schema:Event"}], "schema:rangeIncludes": {"@id": "schema:Thing"}},
+    {"@id": "schema:text", "@type": "rdf:Property", "rdfs:label": "text", "schema:domainIncludes": {"@id": "schema:CreativeWork"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:thumbnailUrl", "@type": "rdf:Property", "rdfs:label": "thumbnailUrl", "schema:domainIncludes": {"@id": "schema:CreativeWork"}, "schema:rangeIncludes": {"@id": "schema:URL"}},
+    {"@id": "schema:articleBody", "@type": "rdf:Property", "rdfs:label": "articleBody", "schema:domainIncludes": {"@id": "schema:Article"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:aggregateRating", "@type": "rdf:Property", "rdfs:label": "aggregateRating", "schema:domainIncludes": [{"@id": "schema:CreativeWork"}, {"@id": "schema:Organization"}, {"@id": "schema:Place"}, {"@id": "schema:Product"}, {"@id": "schema:Event"}, {"@id": "schema:Offer"}], "schema:rangeIncludes": {"@id": "schema:AggregateRating"}},
+    {"@id": "schema:review", "@type": "rdf:Property", "rdfs:label": "review", "schema:domainIncludes": [{"@id": "schema:CreativeWork"}, {"@id": "schema:Organization"}, {"@id": "schema:Place"}, {"@id": "schema:Product"}, {"@id": "schema:Event"}, {"@id": "schema:Offer"}], "schema:rangeIncludes": {"@id": "schema:Review"}},
+    {"@id": "schema:reviewRating", "@type": "rdf:Property", "rdfs:label": "reviewRating", "schema:domainIncludes": {"@id": "schema:Review"}, "schema:rangeIncludes": {"@id": "schema:Rating"}},
+    {"@id": "schema:reviewBody", "@type": "rdf:Property", "rdfs:label": "reviewBody", "schema:domainIncludes": {"@id": "schema:Review"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:itemReviewed", "@type": "rdf:Property", "rdfs:label": "itemReviewed", "schema:domainIncludes": [{"@id": "schema:Review"}, {"@id": "schema:AggregateRating"}], "schema:rangeIncludes": {"@id": "schema:Thing"}},
+    {"@id": "schema:ratingValue", "@type": "rdf:Property", "rdfs:label": "ratingValue", "schema:domainIncludes": {"@id": "schema:Rating"}, "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:bestRating", "@type": "rdf:Property", "rdfs:label": "bestRating", "schema:domainIncludes": {"@id": "schema:Rating"}, "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:worstRating", "@type": "rdf:Property", "rdfs:label": "worstRating", "schema:domainIncludes": {"@id": "schema:Rating"}, "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:ratingCount", "@type": "rdf:Property", "rdfs:label": "ratingCount", "schema:domainIncludes": {"@id": "schema:AggregateRating"}, "schema:rangeIncludes": {"@id": "schema:Integer"}},
+    {"@id": "schema:reviewCount", "@type": "rdf:Property", "rdfs:label": "reviewCount", "schema:domainIncludes": {"@id": "schema:AggregateRating"}, "schema:rangeIncludes": {"@id": "schema:Integer"}},
+    {"@id": "schema:contentUrl", "@type": "rdf:Property", "rdfs:label": "contentUrl", "schema:domainIncludes": {"@id": "schema:MediaObject"}, "schema:rangeIncludes": {"@id": "schema:URL"}},
+    {"@id": "schema:uploadDate", "@type": "rdf:Property", "rdfs:label": "uploadDate", "schema:domainIncludes": {"@id": "schema:MediaObject"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:duration", "@type": "rdf:Property", "rdfs:label": "duration", "schema:domainIncludes": [{"@id": "schema:MediaObject"}, {"@id": "schema:Event"}], "schema:rangeIncludes": {"@id": "schema:Duration"}},
+    {"@id": "schema:givenName", "@type": "rdf:Property", "rdfs:label": "givenName", "schema:domainIncludes": {"@id": "schema:Person"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:familyName", "@type": "rdf:Property", "rdfs:label": "familyName", "schema:domainIncludes": {"@id": "schema:Person"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:additionalName", "@type": "rdf:Property", "rdfs:label": "additionalName", "schema:domainIncludes": {"@id": "schema:Person"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:birthDate", "@type": "rdf:Property", "rdfs:label": "birthDate", "schema:domainIncludes": {"@id": "schema:Person"}, "schema:rangeIncludes": {"@id": "schema:Date"}},
+    {"@id": "schema:jobTitle", "@type": "rdf:Property", "rdfs:label": "jobTitle", "schema:domainIncludes": {"@id": "schema:Person"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:worksFor", "@type": "rdf:Property", "rdfs:label": "worksFor", "schema:domainIncludes": {"@id": "schema:Person"}, "schema:rangeIncludes": {"@id": "schema:Organization"}},
+    {"@id": "schema:email", "@type": "rdf:Property", "rdfs:label": "email", "schema:domainIncludes": [{"@id": "schema:Person"}, {"@id": "schema:Organization"}, {"@id": "schema:ContactPoint"}], "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:telephone", "@type": "rdf:Property", "rdfs:label": "telephone", "schema:domainIncludes": [{"@id": "schema:Person"}, {"@id": "schema:Organization"}, {"@id": "schema:Place"}, {"@id": "schema:ContactPoint"}], "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:address", "@type": "rdf:Property", "rdfs:label": "address", "schema:domainIncludes": [{"@id": "schema:Person"}, {"@id": "schema:Organization"}, {"@id": "schema:Place"}], "schema:rangeIncludes": [{"@id": "schema:PostalAddress"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:logo", "@type": "rdf:Property", "rdfs:label": "logo", "schema:domainIncludes": [{"@id": "schema:Organization"}, {"@id": "schema:Place"}, {"@id": "schema:Product"}, {"@id": "schema:Brand"}], "schema:rangeIncludes": [{"@id": "schema:ImageObject"}, {"@id": "schema:URL"}]},
+    {"@id": "schema:geo", "@type": "rdf:Property", "rdfs:label": "geo", "schema:domainIncludes": {"@id": "schema:Place"}, "schema:rangeIncludes": {"@id": "schema:GeoCoordinates"}},
+    {"@id": "schema:latitude", "@type": "rdf:Property", "rdfs:label": "latitude", "schema:domainIncludes": [{"@id": "schema:GeoCoordinates"}, {"@id": "schema:Place"}], "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:longitude", "@type": "rdf:Property", "rdfs:label": "longitude", "schema:domainIncludes": [{"@id": "schema:GeoCoordinates"}, {"@id": "schema:Place"}], "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:streetAddress", "@type": "rdf:Property", "rdfs:label": "streetAddress", "schema:domainIncludes": {"@id": "schema:PostalAddress"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:addressLocality", "@type": "rdf:Property", "rdfs:label": "addressLocality", "schema:domainIncludes": {"@id": "schema:PostalAddress"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:addressRegion", "@type": "rdf:Property", "rdfs:label": "addressRegion", "schema:domainIncludes": {"@id": "schema:PostalAddress"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:postalCode", "@type": "rdf:Property", "rdfs:label": "postalCode", "schema:domainIncludes": {"@id": "schema:PostalAddress"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:addressCountry", "@type": "rdf:Property", "rdfs:label": "addressCountry", "schema:domainIncludes": {"@id": "schema:PostalAddress"}, "schema:rangeIncludes": [{"@id": "schema:Country"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:brand", "@type": "rdf:Property", "rdfs:label": "brand", "schema:domainIncludes": [{"@id": "schema:Product"}, {"@id": "schema:Organization"}, {"@id": "schema:Person"}], "schema:rangeIncludes": [{"@id": "schema:Brand"}, {"@id": "schema:Organization"}]},
+    {"@id": "schema:sku", "@type": "rdf:Property", "rdfs:label": "sku", "schema:domainIncludes": [{"@id": "schema:Product"}, {"@id": "schema:Offer"}], "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:gtin", "@type": "rdf:Property", "rdfs:label": "gtin", "schema:domainIncludes": [{"@id": "schema:Product"}, {"@id": "schema:Offer"}], "schema:rangeIncludes": [{"@id": "schema:Text"}, {"@id": "schema:URL"}]},
+    {"@id": "schema:mpn", "@type": "rdf:Property", "rdfs:label": "mpn", "schema:domainIncludes": [{"@id": "schema:Product"}, {"@id": "schema:Offer"}], "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:productID", "@type": "rdf:Property", "rdfs:label": "productID", "schema:domainIncludes": {"@id": "schema:Product"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:color", "@type": "rdf:Property", "rdfs:label": "color", "schema:domainIncludes": {"@id": "schema:Product"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:offers", "@type": "rdf:Property", "rdfs:label": "offers", "schema:domainIncludes": [{"@id": "schema:Product"}, {"@id": "schema:CreativeWork"}, {"@id": "schema:Event"}, {"@id": "schema:Place"}], "schema:rangeIncludes": [{"@id": "schema:Offer"}, {"@id": "schema:Demand"}]},
+    {"@id": "schema:price", "@type": "rdf:Property", "rdfs:label": "price", "schema:domainIncludes": [{"@id": "schema:Offer"}, {"@id": "schema:PriceSpecification"}], "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:priceCurrency", "@type": "rdf:Property", "rdfs:label": "priceCurrency", "schema:domainIncludes": [{"@id": "schema:Offer"}, {"@id": "schema:PriceSpecification"}, {"@id": "schema:Event"}], "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:priceValidUntil", "@type": "rdf:Property", "rdfs:label": "priceValidUntil", "schema:domainIncludes": {"@id": "schema:Offer"}, "schema:rangeIncludes": {"@id": "schema:Date"}},
+    {"@id": "schema:availability", "@type": "rdf:Property", "rdfs:label": "availability", "schema:domainIncludes": [{"@id": "schema:Offer"}, {"@id": "schema:Demand"}], "schema:rangeIncludes": {"@id": "schema:ItemAvailability"}},
+    {"@id": "schema:itemCondition", "@type": "rdf:Property", "rdfs:label": "itemCondition", "schema:domainIncludes": [{"@id": "schema:Offer"}, {"@id": "schema:Demand"}, {"@id": "schema:Product"}], "schema:rangeIncludes": {"@id": "schema:OfferItemCondition"}},
+    {"@id": "schema:seller", "@type": "rdf:Property", "rdfs:label": "seller", "schema:domainIncludes": [{"@id": "schema:Offer"}, {"@id": "schema:Demand"}], "schema:rangeIncludes": [{"@id": "schema:Organization"}, {"@id": "schema:Person"}]},
+    {"@id": "schema:recipeIngredient", "@type": "rdf:Property", "rdfs:label": "recipeIngredient", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:recipeInstructions", "@type": "rdf:Property", "rdfs:label": "recipeInstructions", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": [{"@id": "schema:HowToStep"}, {"@id": "schema:ItemList"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:recipeYield", "@type": "rdf:Property", "rdfs:label": "recipeYield", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": [{"@id": "schema:QuantitativeValue"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:recipeCategory", "@type": "rdf:Property", "rdfs:label": "recipeCategory", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:recipeCuisine", "@type": "rdf:Property", "rdfs:label": "recipeCuisine", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:cookTime", "@type": "rdf:Property", "rdfs:label": "cookTime", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:Duration"}},
+    {"@id": "schema:prepTime", "@type": "rdf:Property", "rdfs:label": "prepTime", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:Duration"}},
+    {"@id": "schema:totalTime", "@type": "rdf:Property", "rdfs:label": "totalTime", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:Duration"}},
+    {"@id": "schema:nutrition", "@type": "rdf:Property", "rdfs:label": "nutrition", "schema:domainIncludes": {"@id": "schema:Recipe"}, "schema:rangeIncludes": {"@id": "schema:NutritionInformation"}},
+    {"@id": "schema:calories", "@type": "rdf:Property", "rdfs:label": "calories", "schema:domainIncludes": {"@id": "schema:NutritionInformation"}, "schema:rangeIncludes": {"@id": "schema:Energy"}},
+    {"@id": "schema:startDate", "@type": "rdf:Property", "rdfs:label": "startDate", "schema:domainIncludes": {"@id": "schema:Event"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:endDate", "@type": "rdf:Property", "rdfs:label": "endDate", "schema:domainIncludes": {"@id": "schema:Event"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:location", "@type": "rdf:Property", "rdfs:label": "location", "schema:domainIncludes": [{"@id": "schema:Event"}, {"@id": "schema:Organization"}], "schema:rangeIncludes": [{"@id": "schema:Place"}, {"@id": "schema:PostalAddress"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:organizer", "@type": "rdf:Property", "rdfs:label": "organizer", "schema:domainIncludes": {"@id": "schema:Event"}, "schema:rangeIncludes": [{"@id": "schema:Organization"}, {"@id": "schema:Person"}]},
+    {"@id": "schema:performer", "@type": "rdf:Property", "rdfs:label": "performer", "schema:domainIncludes": {"@id": "schema:Event"}, "schema:rangeIncludes": [{"@id": "schema:Organization"}, {"@id": "schema:Person"}]},
+    {"@id": "schema:eventStatus", "@type": "rdf:Property", "rdfs:label": "eventStatus", "schema:domainIncludes": {"@id": "schema:Event"}, "schema:rangeIncludes": {"@id": "schema:EventStatusType"}},
+    {"@id": "schema:title", "@type": "rdf:Property", "rdfs:label": "title", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:datePosted", "@type": "rdf:Property", "rdfs:label": "datePosted", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:validThrough", "@type": "rdf:Property", "rdfs:label": "validThrough", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": [{"@id": "schema:Date"}, {"@id": "schema:DateTime"}]},
+    {"@id": "schema:hiringOrganization", "@type": "rdf:Property", "rdfs:label": "hiringOrganization", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": {"@id": "schema:Organization"}},
+    {"@id": "schema:jobLocation", "@type": "rdf:Property", "rdfs:label": "jobLocation", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": {"@id": "schema:Place"}},
+    {"@id": "schema:employmentType", "@type": "rdf:Property", "rdfs:label": "employmentType", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:baseSalary", "@type": "rdf:Property", "rdfs:label": "baseSalary", "schema:domainIncludes": {"@id": "schema:JobPosting"}, "schema:rangeIncludes": [{"@id": "schema:MonetaryAmount"}, {"@id": "schema:Number"}, {"@id": "schema:PriceSpecification"}]},
+    {"@id": "schema:currency", "@type": "rdf:Property", "rdfs:label": "currency", "schema:domainIncludes": {"@id": "schema:MonetaryAmount"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:value", "@type": "rdf:Property", "rdfs:label": "value", "schema:domainIncludes": [{"@id": "schema:MonetaryAmount"}, {"@id": "schema:QuantitativeValue"}], "schema:rangeIncludes": [{"@id": "schema:Number"}, {"@id": "schema:QuantitativeValue"}, {"@id": "schema:Text"}, {"@id": "schema:Boolean"}]},
+    {"@id": "schema:minValue", "@type": "rdf:Property", "rdfs:label": "minValue", "schema:domainIncludes": [{"@id": "schema:MonetaryAmount"}, {"@id": "schema:QuantitativeValue"}], "schema:rangeIncludes": {"@id": "schema:Number"}},
+    {"@id": "schema:maxValue", "@type": "rdf:Property", "rdfs:label": "maxValue", "schema:domainIncludes": [{"@id": "schema:MonetaryAmount"}, {"@id": "schema:QuantitativeValue"}], "schema:rangeIncludes": {"@id": "schema:Number"}},
+    {"@id": "schema:unitText", "@type": "rdf:Property", "rdfs:label": "unitText", "schema:domainIncludes": {"@id": "schema:QuantitativeValue"}, "schema:rangeIncludes": {"@id": "schema:Text"}},
+    {"@id": "schema:unitCode", "@type": "rdf:Property", "rdfs:label": "unitCode", "schema:domainIncludes": {"@id": "schema:QuantitativeValue"}, "schema:rangeIncludes": [{"@id": "schema:Text"}, {"@id": "schema:URL"}]},
+    {"@id": "schema:itemListElement", "@type": "rdf:Property", "rdfs:label": "itemListElement", "schema:domainIncludes": {"@id": "schema:ItemList"}, "schema:rangeIncludes": [{"@id": "schema:ListItem"}, {"@id": "schema:Text"}, {"@id": "schema:Thing"}]},
+    {"@id": "schema:numberOfItems", "@type": "rdf:Property", "rdfs:label": "numberOfItems", "schema:domainIncludes": {"@id": "schema:ItemList"}, "schema:rangeIncludes": {"@id": "schema:Integer"}},
+    {"@id": "schema:position", "@type": "rdf:Property", "rdfs:label": "position", "schema:domainIncludes": [{"@id": "schema:ListItem"}, {"@id": "schema:CreativeWork"}], "schema:rangeIncludes": [{"@id": "schema:Integer"}, {"@id": "schema:Text"}]},
+    {"@id": "schema:item", "@type": "rdf:Property", "rdfs:label": "item", "schema:domainIncludes": {"@id": "schema:ListItem"}, "schema:rangeIncludes": {"@id": "schema:Thing"}}
+  ]
+}`
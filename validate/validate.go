@@ -0,0 +1,249 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+// Package validate checks items against the schema.org vocabulary: whether
+// their types and property names exist, whether the properties are expected
+// on the types of the items, and whether the values are of the expected
+// types.
+//
+//	findings := validate.Validate(data)
+//	for _, f := range findings {
+//		fmt.Println(f)
+//	}
+//
+// Items of other vocabularies, and items without type, are not checked
+// themselves, but the items nested in them are.
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/damian-szulc/microdata"
+)
+
+// Finding codes.
+const (
+	// UnknownType reports a schema.org type that is not in the vocabulary.
+	// It is not reported by the bundled vocabulary, which is partial.
+	UnknownType = "unknown-type"
+	// UnknownProperty reports a property that is not in the vocabulary. It
+	// is not reported by the bundled vocabulary, which is partial.
+	UnknownProperty = "unknown-property"
+	// UnexpectedProperty reports a property that is not expected on the
+	// types of the item, e.g. "price" on a Product.
+	UnexpectedProperty = "unexpected-property"
+	// UnexpectedValue reports a value that is not of one of the types
+	// expected by the property, e.g. a Product as "offers".
+	UnexpectedValue = "unexpected-value"
+)
+
+// Finding describes a problem found in an item. Path locates the item,
+// property or value, e.g. "items[0].offers[1].price".
+type Finding struct {
+	Path    string
+	Code    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return f.Path + ": " + f.Code + ": " + f.Message
+}
+
+// Validate validates the items against the bundled schema.org vocabulary.
+// See Vocabulary.Validate.
+func Validate(data *microdata.Microdata) []Finding {
+	return SchemaOrg().Validate(data)
+}
+
+type validator struct {
+	vocab    *Vocabulary
+	findings []Finding
+	// seen holds the items already validated, so that shared and cyclic
+	// items are validated once.
+	seen map[*microdata.Item]bool
+}
+
+// Validate validates the items and the items nested in them. The paths of
+// the findings start with "items[i]".
+func (v *Vocabulary) Validate(data *microdata.Microdata) []Finding {
+	val := &validator{vocab: v, seen: make(map[*microdata.Item]bool)}
+	for i, item := range data.Items {
+		val.validateItem(item, fmt.Sprintf("items[%d]", i))
+	}
+	return val.findings
+}
+
+// ValidateItem validates the item and the items nested in it. The paths of
+// the findings start with a property name of the item, and are empty for the
+// findings about the types of the item.
+func (v *Vocabulary) ValidateItem(item *microdata.Item) []Finding {
+	val := &validator{vocab: v, seen: make(map[*microdata.Item]bool)}
+	val.validateItem(item, "")
+	return val.findings
+}
+
+func (val *validator) add(path, code, format string, args ...interface{}) {
+	val.findings = append(val.findings, Finding{
+		Path:    path,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// classes returns the schema.org types of the item known to the vocabulary,
+// and the unknown ones.
+func (val *validator) classes(item *microdata.Item) (known, unknown []string) {
+	for _, t := range item.Types {
+		term, ok := schemaOrgTerm(t)
+		if !ok {
+			continue
+		}
+		if val.vocab.HasClass(term) {
+			known = append(known, term)
+		} else {
+			unknown = append(unknown, t)
+		}
+	}
+	return known, unknown
+}
+
+// validateItem validates the item at the given path.
+func (val *validator) validateItem(item *microdata.Item, path string) {
+	if item == nil || val.seen[item] {
+		return
+	}
+	val.seen[item] = true
+
+	classes, unknown := val.classes(item)
+	if !val.vocab.partial {
+		for _, t := range unknown {
+			val.add(path, UnknownType, "unknown type %q", t)
+		}
+	}
+
+	for _, name := range propertyNames(item) {
+		term := name
+		if strings.Contains(name, ":") {
+			var ok bool
+			if term, ok = schemaOrgTerm(name); !ok {
+				// A property of another vocabulary.
+				continue
+			}
+		}
+		propertyPath := join(path, name)
+
+		if len(classes) > 0 {
+			val.validateProperty(item, name, term, classes, propertyPath)
+		}
+
+		for j, value := range item.Properties[name] {
+			if value.Kind == microdata.ItemValue {
+				val.validateItem(value.Item, fmt.Sprintf("%s[%d]", propertyPath, j))
+			}
+		}
+	}
+}
+
+// validateProperty checks that the property of an item of the given classes
+// exists, is expected on the classes, and has values of the expected types.
+func (val *validator) validateProperty(item *microdata.Item, name, term string, classes []string, path string) {
+	p, ok := val.vocab.properties[term]
+	if !ok {
+		if !val.vocab.partial {
+			val.add(path, UnknownProperty, "unknown property %q", name)
+		}
+		return
+	}
+
+	if !val.anySubClassOf(classes, p.domains) {
+		val.add(path, UnexpectedProperty, "property %q is not expected on %s", name, strings.Join(classes, " or "))
+	}
+
+	if len(p.ranges) == 0 {
+		return
+	}
+	for j, value := range item.Properties[name] {
+		valuePath := fmt.Sprintf("%s[%d]", path, j)
+		if value.Kind == microdata.ItemValue {
+			if value.Item == nil {
+				continue
+			}
+			// Nested items without known schema.org type are not checked.
+			nested, _ := val.classes(value.Item)
+			if len(nested) > 0 && !val.anySubClassOf(nested, p.ranges) {
+				val.add(valuePath, UnexpectedValue, "property %q expects %s, but has %s",
+					name, strings.Join(p.ranges, " or "), strings.Join(nested, " and "))
+			}
+			continue
+		}
+		if !val.accepts(p.ranges, value) {
+			val.add(valuePath, UnexpectedValue, "property %q expects %s, but has %s value %q",
+				name, strings.Join(p.ranges, " or "), value.Kind, value.Raw)
+		}
+	}
+}
+
+// anySubClassOf reports whether any of the classes is a subclass of any of
+// the given ancestors.
+func (val *validator) anySubClassOf(classes, ancestors []string) bool {
+	for _, c := range classes {
+		for _, a := range ancestors {
+			if val.vocab.IsSubClassOf(c, a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accepts reports whether the value, which is not an item, is acceptable for
+// any of the given range classes. Text accepts any value; numbers, dates and
+// booleans accept values in their format. Enumerations and quantities, such as
+// Duration, accept text; other classes accept URLs referencing an instance.
+func (val *validator) accepts(ranges []string, value *microdata.Value) bool {
+	vocab := val.vocab
+	for _, r := range ranges {
+		switch {
+		case vocab.IsSubClassOf(r, "Text"):
+			return true
+		case vocab.IsSubClassOf(r, "Number"):
+			if _, err := value.AsFloat(); err == nil {
+				return true
+			}
+		case vocab.IsSubClassOf(r, "Date"), vocab.IsSubClassOf(r, "DateTime"), vocab.IsSubClassOf(r, "Time"):
+			if _, err := value.AsTime(); err == nil {
+				return true
+			}
+		case vocab.IsSubClassOf(r, "Boolean"):
+			s := strings.ToLower(strings.TrimSpace(value.Raw))
+			if s == "true" || s == "false" || strings.HasSuffix(s, "/true") || strings.HasSuffix(s, "/false") {
+				return true
+			}
+		case vocab.IsSubClassOf(r, "Enumeration"), vocab.IsSubClassOf(r, "Quantity"):
+			return true
+		case !vocab.isDataType(r) && value.Kind == microdata.URLValue:
+			return true
+		}
+	}
+	return false
+}
+
+// join returns the path of the named property of the item at the given path.
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// propertyNames returns the property names of the item in sorted order.
+func propertyNames(item *microdata.Item) []string {
+	names := make([]string, 0, len(item.Properties))
+	for name := range item.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,138 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/damian-szulc/microdata"
+)
+
+func parse(html string, t *testing.T) *microdata.Microdata {
+	data, err := microdata.ParseHTML(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// completeVocabulary returns the bundled vocabulary as if it were complete,
+// reporting unknown types and properties.
+func completeVocabulary(t *testing.T) *Vocabulary {
+	v, err := LoadVocabulary(strings.NewReader(schemaOrgSnapshot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func findingStrings(findings []Finding) []string {
+	var result []string
+	for _, f := range findings {
+		result = append(result, f.String())
+	}
+	return result
+}
+
+func TestValidate(t *testing.T) {
+	data := parse(`
+		<div itemscope itemtype="http://schema.org/Product">
+			<span itemprop="name">Anvil</span>
+			<span itemprop="colour">black</span>
+			<span itemprop="startDate">2015-07-19</span>
+			<div itemprop="offers" itemscope itemtype="http://schema.org/Offer">
+				<span itemprop="price">119.99</span>
+				<link itemprop="availability" href="http://schema.org/InStock">
+				<span itemprop="priceValidUntil">next week</span>
+			</div>
+			<div itemprop="offers" itemscope itemtype="http://schema.org/Product">
+				<span itemprop="name">Hammer</span>
+			</div>
+			<div itemprop="aggregateRating" itemscope itemtype="http://schema.org/AggregateRating">
+				<span itemprop="ratingValue">4.4</span>
+				<span itemprop="reviewCount">many</span>
+			</div>
+			<span itemprop="http://example.com/vocab#weight">100kg</span>
+		</div>
+		<div itemscope itemtype="http://schema.org/Prodcut">
+			<span itemprop="name">Typo</span>
+		</div>
+		<div itemscope itemtype="http://example.com/Thing">
+			<span itemprop="whatever">Other vocabulary</span>
+		</div>`, t)
+
+	result := findingStrings(completeVocabulary(t).Validate(data))
+	expected := []string{
+		`items[0].aggregateRating[0].reviewCount[0]: unexpected-value: property "reviewCount" expects Integer, but has text value "many"`,
+		`items[0].colour: unknown-property: unknown property "colour"`,
+		`items[0].offers[1]: unexpected-value: property "offers" expects Offer or Demand, but has Product`,
+		`items[0].offers[0].priceValidUntil[0]: unexpected-value: property "priceValidUntil" expects Date, but has text value "next week"`,
+		`items[0].startDate: unexpected-property: property "startDate" is not expected on Product`,
+		`items[1]: unknown-type: unknown type "http://schema.org/Prodcut"`,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", strings.Join(expected, "\n"), strings.Join(result, "\n"))
+	}
+
+	// The bundled vocabulary is partial, and does not report unknown terms.
+	result = findingStrings(Validate(data))
+	expected = []string{expected[0], expected[2], expected[3], expected[4]}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", strings.Join(expected, "\n"), strings.Join(result, "\n"))
+	}
+}
+
+func TestValidatePartialVocabulary(t *testing.T) {
+	data := parse(`
+		<div itemscope itemtype="https://schema.org/Book">
+			<span itemprop="name">Moby-Dick</span>
+			<span itemprop="isbn">978-0142437247</span>
+		</div>
+		<div itemscope itemtype="https://schema.org/Product">
+			<span itemprop="name">Anvil</span>
+			<span itemprop="gtin13">4006381333931</span>
+		</div>`, t)
+
+	if findings := Validate(data); len(findings) != 0 {
+		t.Errorf("Result should have had no findings, but it had %v", findings)
+	}
+}
+
+func TestValidateItem(t *testing.T) {
+	data, err := microdata.ParseJSONLD(strings.NewReader(`
+		<script type="application/ld+json">
+		{
+			"@context": "https://schema.org",
+			"@type": "Recipe",
+			"name": "Pancakes",
+			"cookTime": "PT10M",
+			"author": {"@type": "Person", "name": "Jane"},
+			"nutrition": {"@type": "NutritionInformation", "calories": "240 calories"},
+			"recipeIngredient": ["flour", "milk"]
+		}
+		</script>`), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if findings := SchemaOrg().ValidateItem(data.Items[0]); len(findings) != 0 {
+		t.Errorf("Result should have had no findings, but it had %v", findings)
+	}
+}
+
+func TestValidateCycle(t *testing.T) {
+	data := parse(`
+		<div itemscope itemtype="http://schema.org/Person" id="a" itemref="b">
+			<span itemprop="name">A</span>
+		</div>
+		<div id="b">
+			<div itemprop="knows" itemscope itemtype="http://schema.org/Person" itemref="a"></div>
+		</div>`, t)
+
+	if findings := completeVocabulary(t).Validate(data); len(findings) != 1 || findings[0].Code != UnknownProperty {
+		t.Errorf("Result should have had an unknown property finding, but it had %v", findings)
+	}
+}
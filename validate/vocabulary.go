@@ -0,0 +1,244 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	rdfsClass      = "http://www.w3.org/2000/01/rdf-schema#Class"
+	rdfsSubClassOf = "http://www.w3.org/2000/01/rdf-schema#subClassOf"
+	rdfProperty    = "http://www.w3.org/1999/02/22-rdf-syntax-ns#Property"
+)
+
+// schemaOrgPrefixes holds the IRI prefixes of schema.org terms.
+var schemaOrgPrefixes = []string{"https://schema.org/", "http://schema.org/"}
+
+// schemaOrgTerm returns the schema.org term of the given IRI, e.g. "Person"
+// for "http://schema.org/Person".
+func schemaOrgTerm(iri string) (string, bool) {
+	for _, prefix := range schemaOrgPrefixes {
+		if strings.HasPrefix(iri, prefix) && len(iri) > len(prefix) {
+			return iri[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+type class struct {
+	parents  []string
+	dataType bool
+}
+
+type property struct {
+	domains []string
+	ranges  []string
+}
+
+// Vocabulary holds the classes and properties of the schema.org vocabulary,
+// by term.
+type Vocabulary struct {
+	classes    map[string]*class
+	properties map[string]*property
+	// partial is set for vocabularies that do not define every schema.org
+	// term, such as the bundled one; types and properties missing from them
+	// are not reported as unknown.
+	partial bool
+}
+
+// LoadVocabulary reads a schema.org vocabulary in the JSON-LD format of the
+// schema.org releases: a @graph of rdfs:Class and rdf:Property nodes, with
+// rdfs:subClassOf, schema:domainIncludes and schema:rangeIncludes. Nodes of
+// other vocabularies are ignored.
+func LoadVocabulary(r io.Reader) (*Vocabulary, error) {
+	var doc struct {
+		Context interface{}              `json:"@context"`
+		Graph   []map[string]interface{} `json:"@graph"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	prefixes := make(map[string]string)
+	if ctx, ok := doc.Context.(map[string]interface{}); ok {
+		for k, v := range ctx {
+			if s, ok := v.(string); ok {
+				prefixes[k] = s
+			}
+		}
+	}
+	expand := func(s string) string {
+		if i := strings.IndexByte(s, ':'); i > 0 && !strings.HasPrefix(s[i+1:], "//") {
+			if iri, ok := prefixes[s[:i]]; ok {
+				return iri + s[i+1:]
+			}
+		}
+		return s
+	}
+
+	v := &Vocabulary{
+		classes:    make(map[string]*class),
+		properties: make(map[string]*property),
+	}
+
+	for _, node := range doc.Graph {
+		id, _ := node["@id"].(string)
+		term, ok := schemaOrgTerm(expand(id))
+		if !ok {
+			continue
+		}
+
+		// Node keys are compact IRIs; index them by expanded IRI.
+		values := make(map[string][]string)
+		for k, val := range node {
+			values[expand(k)] = ids(val, expand)
+		}
+
+		for _, typ := range values["@type"] {
+			switch typ {
+			case rdfsClass:
+				c := &class{}
+				for _, parent := range values[rdfsSubClassOf] {
+					if p, ok := schemaOrgTerm(parent); ok {
+						c.parents = append(c.parents, p)
+					}
+				}
+				v.classes[term] = c
+			case rdfProperty:
+				p := &property{}
+				for _, prefix := range schemaOrgPrefixes {
+					p.domains = append(p.domains, terms(values[prefix+"domainIncludes"])...)
+					p.ranges = append(p.ranges, terms(values[prefix+"rangeIncludes"])...)
+				}
+				v.properties[term] = p
+			}
+		}
+		for _, typ := range values["@type"] {
+			if t, _ := schemaOrgTerm(typ); t == "DataType" && v.classes[term] != nil {
+				v.classes[term].dataType = true
+			}
+		}
+	}
+
+	if len(v.classes) == 0 {
+		return nil, errors.New("validate: no schema.org classes found in vocabulary")
+	}
+	return v, nil
+}
+
+// ids returns the expanded IRIs of the given JSON-LD value: a string, a node
+// reference {"@id": ...} or an array of either.
+func ids(v interface{}, expand func(string) string) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{expand(v)}
+	case map[string]interface{}:
+		if id, ok := v["@id"].(string); ok {
+			return []string{expand(id)}
+		}
+	case []interface{}:
+		var result []string
+		for _, elem := range v {
+			result = append(result, ids(elem, expand)...)
+		}
+		return result
+	}
+	return nil
+}
+
+// terms returns the schema.org terms of the given IRIs.
+func terms(iris []string) []string {
+	var result []string
+	for _, iri := range iris {
+		if term, ok := schemaOrgTerm(iri); ok {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+// LoadVocabularyFile reads a schema.org vocabulary from the named file. See
+// LoadVocabulary.
+func LoadVocabularyFile(name string) (*Vocabulary, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadVocabulary(f)
+}
+
+var (
+	schemaOrgOnce sync.Once
+	schemaOrg     *Vocabulary
+)
+
+// SchemaOrg returns the bundled schema.org vocabulary. It is a subset of
+// schema.org covering the types and properties commonly found in structured
+// data, so types and properties it does not define are not reported as
+// unknown, and items of such types are not checked. Use LoadVocabularyFile to
+// validate against a complete release.
+func SchemaOrg() *Vocabulary {
+	schemaOrgOnce.Do(func() {
+		v, err := LoadVocabulary(strings.NewReader(schemaOrgSnapshot))
+		if err != nil {
+			panic("validate: invalid bundled vocabulary: " + err.Error())
+		}
+		v.partial = true
+		schemaOrg = v
+	})
+	return schemaOrg
+}
+
+// HasClass reports whether the vocabulary defines the given class, e.g.
+// "Product".
+func (v *Vocabulary) HasClass(name string) bool {
+	_, ok := v.classes[name]
+	return ok
+}
+
+// HasProperty reports whether the vocabulary defines the given property, e.g.
+// "offers".
+func (v *Vocabulary) HasProperty(name string) bool {
+	_, ok := v.properties[name]
+	return ok
+}
+
+// IsSubClassOf reports whether the class is the given ancestor class, or one
+// of its descendants.
+func (v *Vocabulary) IsSubClassOf(name, ancestor string) bool {
+	seen := make(map[string]bool)
+	pending := []string{name}
+	for len(pending) > 0 {
+		name, pending = pending[0], pending[1:]
+		if name == ancestor {
+			return true
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if c, ok := v.classes[name]; ok {
+			pending = append(pending, c.parents...)
+		}
+	}
+	return false
+}
+
+// isDataType reports whether the class is a data type, such as Text or
+// Integer, or a descendant of one, such as URL.
+func (v *Vocabulary) isDataType(name string) bool {
+	for dataType, c := range v.classes {
+		if c.dataType && v.IsSubClassOf(name, dataType) {
+			return true
+		}
+	}
+	return false
+}
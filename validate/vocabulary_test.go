@@ -0,0 +1,102 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package validate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var vocabularySnippet = `{
+	"@context": {"rdfs": "http://www.w3.org/2000/01/rdf-schema#", "s": "http://schema.org/"},
+	"@graph": [
+		{"@id": "s:Thing", "@type": "rdfs:Class"},
+		{"@id": "s:Text", "@type": ["s:DataType", "rdfs:Class"]},
+		{"@id": "s:URL", "@type": "rdfs:Class", "rdfs:subClassOf": {"@id": "s:Text"}},
+		{"@id": "http://schema.org/Vehicle", "@type": "rdfs:Class", "rdfs:subClassOf": [{"@id": "s:Thing"}]},
+		{"@id": "s:Car", "@type": "rdfs:Class", "rdfs:subClassOf": "s:Vehicle"},
+		{"@id": "s:wheels", "@type": "http://www.w3.org/1999/02/22-rdf-syntax-ns#Property",
+			"s:domainIncludes": {"@id": "s:Vehicle"}, "s:rangeIncludes": [{"@id": "s:Text"}]},
+		{"@id": "http://example.com/Other", "@type": "rdfs:Class"}
+	]
+}`
+
+func TestLoadVocabulary(t *testing.T) {
+	v, err := LoadVocabulary(strings.NewReader(vocabularySnippet))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var testTable = []struct {
+		name     string
+		result   bool
+		expected bool
+	}{
+		{"HasClass(Car)", v.HasClass("Car"), true},
+		{"HasClass(Other)", v.HasClass("Other"), false},
+		{"HasProperty(wheels)", v.HasProperty("wheels"), true},
+		{"IsSubClassOf(Car, Thing)", v.IsSubClassOf("Car", "Thing"), true},
+		{"IsSubClassOf(Thing, Car)", v.IsSubClassOf("Thing", "Car"), false},
+		{"isDataType(URL)", v.isDataType("URL"), true},
+		{"isDataType(Car)", v.isDataType("Car"), false},
+	}
+
+	for _, test := range testTable {
+		if test.result != test.expected {
+			t.Errorf("Result of %s should have been %v, but it was %v", test.name, test.expected, test.result)
+		}
+	}
+
+	if p := v.properties["wheels"]; p == nil || p.domains[0] != "Vehicle" || p.ranges[0] != "Text" {
+		t.Errorf("Result should have had domain Vehicle and range Text, but it was %+v", p)
+	}
+}
+
+func TestLoadVocabularyErrors(t *testing.T) {
+	if _, err := LoadVocabulary(strings.NewReader(`{"@graph": []}`)); err == nil {
+		t.Error("Result should have been an error for a vocabulary without classes")
+	}
+	if _, err := LoadVocabulary(strings.NewReader(`{`)); err == nil {
+		t.Error("Result should have been an error for invalid JSON")
+	}
+	if _, err := LoadVocabularyFile(filepath.Join("testdata", "missing.jsonld")); err == nil {
+		t.Error("Result should have been an error for a missing file")
+	}
+}
+
+func TestLoadVocabularyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "schema.jsonld")
+	if err := ioutil.WriteFile(name, []byte(vocabularySnippet), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := LoadVocabularyFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !v.HasClass("Car") {
+		t.Error("Result should have had the class Car")
+	}
+}
+
+func TestSchemaOrg(t *testing.T) {
+	v := SchemaOrg()
+	for _, class := range []string{"Product", "Offer", "Recipe", "Event", "JobPosting", "BreadcrumbList"} {
+		if !v.HasClass(class) {
+			t.Errorf("Result should have had the class %s", class)
+		}
+	}
+	if !v.IsSubClassOf("NewsArticle", "CreativeWork") {
+		t.Error("Result should have had NewsArticle as subclass of CreativeWork")
+	}
+}
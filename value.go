@@ -0,0 +1,170 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueKind identifies the kind of a property value, which follows from the
+// element the value was read from.
+type ValueKind int
+
+const (
+	// TextValue is the text content or content attribute of an element.
+	TextValue ValueKind = iota
+	// URLValue is the absolute URL from the href, src or data attribute of
+	// an element.
+	URLValue
+	// DateTimeValue is the datetime attribute of a time element.
+	DateTimeValue
+	// NumberValue is the value attribute of a data or meter element, when
+	// it is a number. Other value attributes are TextValue values.
+	NumberValue
+	// ItemValue is a nested item.
+	ItemValue
+)
+
+var valueKindNames = [...]string{
+	TextValue:     "text",
+	URLValue:      "url",
+	DateTimeValue: "datetime",
+	NumberValue:   "number",
+	ItemValue:     "item",
+}
+
+func (k ValueKind) String() string {
+	if k < 0 || int(k) >= len(valueKindNames) {
+		return "ValueKind(" + strconv.Itoa(int(k)) + ")"
+	}
+	return valueKindNames[k]
+}
+
+// Value is the value of a property. It is either a string, held in Raw, or a
+// nested item, held in Item.
+type Value struct {
+	Kind ValueKind
+	// Raw is the string value as found in the document. URLs are resolved
+	// against the base URL. Raw is empty for item values.
+	Raw string
+	// Element is the name of the element the value was read from.
+	Element string
+	// Item is the nested item of an item value.
+	Item *Item
+	// Position locates the property element of the value in the source. It
+	// is only set when parsing with WithPositions, and is not part of the
+	// JSON encoding.
+	Position *Position
+}
+
+// dateTimeLayouts lists the layouts of the date and time strings accepted by
+// the HTML datetime attribute.
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05.999999999-0700",
+	"2006-01-02T15:04-0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+	"15:04:05.999999999",
+	"15:04",
+}
+
+// String returns the string value. Item values return the empty string.
+func (v *Value) String() string {
+	return v.Raw
+}
+
+// AsItem returns the nested item of an item value, or nil for other values.
+func (v *Value) AsItem() *Item {
+	if v.Kind != ItemValue {
+		return nil
+	}
+	return v.Item
+}
+
+// AsURL parses the string value as URL.
+func (v *Value) AsURL() (*url.URL, error) {
+	if v.Kind == ItemValue {
+		return nil, fmt.Errorf("microdata: cannot convert %s value to URL", v.Kind)
+	}
+	return url.Parse(strings.TrimSpace(v.Raw))
+}
+
+// AsTime parses the string value as a date, a time or a date and time in one
+// of the formats allowed for the HTML datetime attribute. Values without a
+// time zone offset are interpreted as UTC.
+func (v *Value) AsTime() (time.Time, error) {
+	if v.Kind == ItemValue {
+		return time.Time{}, fmt.Errorf("microdata: cannot convert %s value to time", v.Kind)
+	}
+
+	s := strings.TrimSpace(v.Raw)
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("microdata: cannot parse %q as date or time", v.Raw)
+}
+
+// numberPattern matches decimal numbers, with optional exponent.
+var numberPattern = regexp.MustCompile(`^[+-]?(?:\d+\.?\d*|\.\d+)(?:[eE][+-]?\d+)?$`)
+
+// isNumber reports whether the given string, without surrounding whitespace,
+// is a decimal number.
+func isNumber(s string) bool {
+	return numberPattern.MatchString(strings.TrimSpace(s))
+}
+
+// AsFloat parses the string value as floating point number.
+func (v *Value) AsFloat() (float64, error) {
+	if v.Kind == ItemValue {
+		return 0, fmt.Errorf("microdata: cannot convert %s value to number", v.Kind)
+	}
+	return strconv.ParseFloat(strings.TrimSpace(v.Raw), 64)
+}
+
+// MarshalJSON encodes the value as JSON string, or as JSON object for item
+// values, which matches the JSON format of the microdata specification.
+func (v *Value) MarshalJSON() ([]byte, error) {
+	if v.Kind == ItemValue {
+		return json.Marshal(v.Item)
+	}
+	return json.Marshal(v.Raw)
+}
+
+// UnmarshalJSON decodes a value encoded by MarshalJSON. Strings decode to text
+// values, since the JSON format does not record the kind of a value.
+func (v *Value) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		item := NewItem()
+		if err := json.Unmarshal(b, item); err != nil {
+			return err
+		}
+		*v = Value{Kind: ItemValue, Item: item}
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*v = Value{Kind: TextValue, Raw: s}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestValueKind(t *testing.T) {
+	html := `
+		<div itemscope itemtype="http://example.com/Event">
+			<span itemprop="name">Concert</span>
+			<meta itemprop="keywords" content="music" />
+			<a itemprop="url" href="/concert">Tickets</a>
+			<time itemprop="startDate" datetime="2015-07-19T20:00:00+02:00">Sunday</time>
+			<data itemprop="capacity" value="400">four hundred</data>
+			<meter itemprop="rating" min="0" max="5" value="4.5">4.5 stars</meter>
+			<data itemprop="wkn" value="wkn:A2JAE9">A2JAE9</data>
+			<data itemprop="code" value="-1.5e3">minus 1500</data>
+			<div itemprop="location" itemscope itemtype="http://example.com/Place"></div>
+		</div>`
+
+	var testTable = []struct {
+		propName string
+		kind     ValueKind
+		element  string
+	}{
+		{"name", TextValue, "span"},
+		{"keywords", TextValue, "meta"},
+		{"url", URLValue, "a"},
+		{"startDate", DateTimeValue, "time"},
+		{"capacity", NumberValue, "data"},
+		{"rating", NumberValue, "meter"},
+		{"wkn", TextValue, "data"},
+		{"code", NumberValue, "data"},
+		{"location", ItemValue, "div"},
+	}
+
+	data := ParseData(html, t)
+
+	for _, test := range testTable {
+		value := data.Items[0].Properties[test.propName][0]
+		if value.Kind != test.kind {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.kind, value.Kind)
+		}
+		if value.Element != test.element {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.element, value.Element)
+		}
+	}
+}
+
+func TestValueAsTime(t *testing.T) {
+	var testTable = []struct {
+		raw      string
+		expected time.Time
+	}{
+		{"2015-07-19T20:00:00+02:00", time.Date(2015, 7, 19, 18, 0, 0, 0, time.UTC)},
+		{"2015-07-19T20:00Z", time.Date(2015, 7, 19, 20, 0, 0, 0, time.UTC)},
+		{"2015-07-19 20:00", time.Date(2015, 7, 19, 20, 0, 0, 0, time.UTC)},
+		{"2015-07-19", time.Date(2015, 7, 19, 0, 0, 0, 0, time.UTC)},
+		{"2015-07", time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range testTable {
+		value := &Value{Kind: DateTimeValue, Raw: test.raw}
+		result, err := value.AsTime()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !result.Equal(test.expected) {
+			t.Errorf("Result should have been \"%s\", but it was \"%s\"", test.expected, result)
+		}
+	}
+
+	if _, err := (&Value{Kind: DateTimeValue, Raw: "Sunday"}).AsTime(); err == nil {
+		t.Error("Error should have been returned for an invalid date")
+	}
+}
+
+func TestValueAsFloat(t *testing.T) {
+	value := &Value{Kind: NumberValue, Raw: " 4.5 "}
+	result, err := value.AsFloat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := 4.5
+	if result != expected {
+		t.Errorf("Result should have been \"%f\", but it was \"%f\"", expected, result)
+	}
+
+	if _, err := (&Value{Kind: ItemValue, Item: NewItem()}).AsFloat(); err == nil {
+		t.Error("Error should have been returned for an item value")
+	}
+}
+
+func TestValueAsURL(t *testing.T) {
+	value := &Value{Kind: URLValue, Raw: "http://example.com/concert"}
+	result, err := value.AsURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "example.com"
+	if result.Host != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result.Host)
+	}
+}
+
+func TestValueAsItem(t *testing.T) {
+	item := NewItem()
+	if result := (&Value{Kind: ItemValue, Item: item}).AsItem(); result != item {
+		t.Errorf("Result should have been \"%v\", but it was \"%v\"", item, result)
+	}
+	if result := (&Value{Kind: TextValue, Raw: "text"}).AsItem(); result != nil {
+		t.Errorf("Result should have been nil, but it was \"%v\"", result)
+	}
+}
+
+func TestValueJSONRoundTrip(t *testing.T) {
+	data := ParseData(blogSnippet, t)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded Microdata
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != string(b) {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", b, result)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The item types of the vCard and vEvent vocabularies of the HTML microdata
+// specification.
+const (
+	HCardType  = "http://microformats.org/profile/hcard"
+	VEventType = "http://microformats.org/profile/hcalendar#vevent"
+)
+
+// hasSchemaOrgType reports whether the item has one of the given schema.org
+// types, over http or https.
+func hasSchemaOrgType(item *Item, names ...string) bool {
+	for _, name := range names {
+		if item.HasType(schemaOrgPrefixes[0] + name) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentLines writes the content lines of vCard and iCalendar objects.
+type contentLines struct {
+	buf strings.Builder
+}
+
+// maxLineOctets is the length in octets above which content lines are folded.
+const maxLineOctets = 75
+
+// add writes a content line with the given name, including any parameters,
+// and value. The value must already be escaped. Control characters, which
+// content lines cannot hold, are removed from both, so that values read from
+// a page cannot start content lines of their own. Lines longer than 75 octets
+// are folded, without splitting UTF-8 sequences.
+func (c *contentLines) add(name, value string) {
+	line := strings.Map(dropControl, name) + ":" + strings.Map(dropControl, value)
+	limit := maxLineOctets
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		c.buf.WriteString(line[:i])
+		c.buf.WriteString("\r\n ")
+		line = line[i:]
+		// Continuation lines start with a space.
+		limit = maxLineOctets - 1
+	}
+	c.buf.WriteString(line)
+	c.buf.WriteString("\r\n")
+}
+
+func (c *contentLines) String() string {
+	return c.buf.String()
+}
+
+// dropControl drops the control characters other than horizontal tab, as a
+// mapping for strings.Map.
+func dropControl(r rune) rune {
+	if r != '\t' && unicode.IsControl(r) {
+		return -1
+	}
+	return r
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// escapeText escapes a TEXT value as in RFC 6350 and RFC 5545. Surrounding
+// whitespace is removed.
+func escapeText(s string) string {
+	return textEscaper.Replace(strings.TrimSpace(s))
+}
+
+// stringValues returns the trimmed, non-empty string values of the property
+// at the given path.
+func stringValues(item *Item, path string) []string {
+	var values []string
+	for _, value := range item.GetAll(path) {
+		if value.Kind != ItemValue {
+			if s := strings.TrimSpace(value.Raw); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// component returns a component of a structured value, such as N or ADR: the
+// escaped string values of the property at the given path, separated by
+// commas.
+func component(item *Item, path string) string {
+	values := stringValues(item, path)
+	for i, v := range values {
+		values[i] = escapeText(v)
+	}
+	return strings.Join(values, ",")
+}
+
+// structured returns a structured value of the given item, with the
+// components read from the given paths.
+func structured(item *Item, paths ...string) string {
+	components := make([]string, len(paths))
+	for i, path := range paths {
+		if path != "" {
+			components[i] = component(item, path)
+		}
+	}
+	return strings.Join(components, ";")
+}
+
+// typeParam returns the TYPE parameter holding the values of the type
+// property of the given item, or the empty string when it has none. Values
+// holding ":", ";" or "," are quoted, and double quotes, which parameter
+// values cannot hold, are dropped.
+func typeParam(item *Item) string {
+	types := stringValues(item, "type")
+	if len(types) == 0 {
+		return ""
+	}
+	for i, t := range types {
+		t = strings.ToLower(strings.Replace(t, `"`, "", -1))
+		if strings.ContainsAny(t, ":;,") {
+			t = `"` + t + `"`
+		}
+		types[i] = t
+	}
+	return ";TYPE=" + strings.Join(types, ",")
+}
+
+// isoDatePattern matches ISO 8601 dates in the extended format.
+var isoDatePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// vcardDate returns the given date in the basic format used by vCard, e.g.
+// "19780101". Other values are returned unchanged.
+func vcardDate(s string) string {
+	if isoDatePattern.MatchString(s) {
+		return strings.Replace(s, "-", "", -1)
+	}
+	return s
+}
+
+// vcardProperties maps the text and URI properties of the hCard vocabulary to
+// vCard properties.
+var vcardProperties = []struct {
+	property, name string
+	uri            bool
+}{
+	{"nickname", "NICKNAME", false},
+	{"photo", "PHOTO", true},
+	{"impp", "IMPP", true},
+	{"lang", "LANG", false},
+	{"tz", "TZ", false},
+	{"title", "TITLE", false},
+	{"role", "ROLE", false},
+	{"logo", "LOGO", true},
+	{"categories", "CATEGORIES", false},
+	{"note", "NOTE", false},
+	{"rev", "REV", false},
+	{"sound", "SOUND", true},
+	{"uid", "UID", true},
+	{"url", "URL", true},
+	{"key", "KEY", true},
+}
+
+// ToVCard returns the RFC 6350 vCard of the given item, which must be an
+// hCard item, with the HCardType of the vocabulary of the HTML microdata
+// specification, or a schema.org Person or Organization item.
+func ToVCard(item *Item) (string, error) {
+	c := &contentLines{}
+	c.add("BEGIN", "VCARD")
+	c.add("VERSION", "4.0")
+
+	switch {
+	case item.HasType(HCardType):
+		writeHCard(c, item)
+	case hasSchemaOrgType(item, "Person"):
+		writeSchemaOrgContact(c, item, false)
+	case hasSchemaOrgType(item, "Organization"):
+		writeSchemaOrgContact(c, item, true)
+	default:
+		var types []string
+		if item != nil {
+			types = item.Types
+		}
+		return "", fmt.Errorf("microdata: cannot convert item of type %q to vCard", strings.Join(types, " "))
+	}
+
+	c.add("END", "VCARD")
+	return c.String(), nil
+}
+
+// writeHCard writes the properties of an hCard item.
+func writeHCard(c *contentLines, item *Item) {
+	n := item.GetItem("n")
+
+	fn := item.GetString("fn")
+	if fn == "" && n != nil {
+		fn = strings.Join(append(stringValues(n, "given-name"), stringValues(n, "family-name")...), " ")
+	}
+	if fn == "" {
+		fn = item.GetString("org")
+		if org := item.GetItem("org"); fn == "" && org != nil {
+			fn = org.GetString("organization-name")
+		}
+	}
+	c.add("FN", escapeText(fn))
+
+	if n != nil {
+		c.add("N", structured(n, "family-name", "given-name", "additional-name", "honorific-prefix", "honorific-suffix"))
+	}
+
+	for _, bday := range stringValues(item, "bday") {
+		c.add("BDAY", escapeText(vcardDate(bday)))
+	}
+
+	for _, adr := range item.GetItems("adr") {
+		c.add("ADR"+typeParam(adr), structured(adr,
+			"post-office-box", "extended-address", "street-address", "locality", "region", "postal-code", "country-name"))
+	}
+
+	for _, name := range []string{"tel", "email"} {
+		for _, value := range item.GetAll(name) {
+			param, s := "", strings.TrimSpace(value.Raw)
+			if value.Kind == ItemValue {
+				param, s = typeParam(value.Item), value.Item.GetString("value")
+			}
+			if name == "email" {
+				s = strings.TrimPrefix(s, "mailto:")
+			}
+			if s != "" {
+				c.add(strings.ToUpper(name)+param, escapeText(s))
+			}
+		}
+	}
+
+	if geo := item.GetItem("geo"); geo != nil {
+		c.add("GEO", "geo:"+strings.TrimSpace(geo.GetString("latitude"))+","+strings.TrimSpace(geo.GetString("longitude")))
+	}
+
+	for _, value := range item.GetAll("org") {
+		if value.Kind == ItemValue {
+			c.add("ORG", structured(value.Item, "organization-name", "organization-unit"))
+		} else if s := escapeText(value.Raw); s != "" {
+			c.add("ORG", s)
+		}
+	}
+
+	for _, p := range vcardProperties {
+		for _, s := range stringValues(item, p.property) {
+			if !p.uri {
+				s = escapeText(s)
+			}
+			c.add(p.name, s)
+		}
+	}
+}
+
+// writeSchemaOrgContact writes the properties of a schema.org Person or
+// Organization item.
+func writeSchemaOrgContact(c *contentLines, item *Item, org bool) {
+	fn := item.GetString("name")
+	if fn == "" {
+		fn = strings.Join(append(stringValues(item, "givenName"), stringValues(item, "familyName")...), " ")
+	}
+	if org {
+		c.add("KIND", "org")
+	}
+	c.add("FN", escapeText(fn))
+
+	n := structured(item, "familyName", "givenName", "additionalName", "honorificPrefix", "honorificSuffix")
+	if n != ";;;;" {
+		c.add("N", n)
+	}
+
+	for _, s := range stringValues(item, "alternateName") {
+		c.add("NICKNAME", escapeText(s))
+	}
+	for _, s := range stringValues(item, "birthDate") {
+		c.add("BDAY", escapeText(vcardDate(s)))
+	}
+
+	for _, value := range item.GetAll("address") {
+		if value.Kind == ItemValue {
+			adr := value.Item
+			country := component(adr, "addressCountry")
+			if country == "" {
+				country = component(adr, "addressCountry.name")
+			}
+			c.add("ADR", strings.Join([]string{
+				component(adr, "postOfficeBoxNumber"),
+				"",
+				component(adr, "streetAddress"),
+				component(adr, "addressLocality"),
+				component(adr, "addressRegion"),
+				component(adr, "postalCode"),
+				country,
+			}, ";"))
+		} else if s := escapeText(value.Raw); s != "" {
+			// A free-form address is kept as street address.
+			c.add("ADR", ";;"+s+";;;;")
+		}
+	}
+
+	for _, s := range stringValues(item, "telephone") {
+		c.add("TEL", escapeText(s))
+	}
+	for _, s := range stringValues(item, "email") {
+		c.add("EMAIL", escapeText(strings.TrimPrefix(s, "mailto:")))
+	}
+
+	if org {
+		c.add("ORG", escapeText(fn))
+	} else {
+		for _, s := range stringValues(item, "jobTitle") {
+			c.add("TITLE", escapeText(s))
+		}
+		for _, name := range []string{"worksFor", "affiliation"} {
+			for _, value := range item.GetAll(name) {
+				s := value.Raw
+				if value.Kind == ItemValue {
+					s = value.Item.GetString("name")
+				}
+				if s = escapeText(s); s != "" {
+					c.add("ORG", s)
+				}
+			}
+		}
+	}
+
+	image, name := "image", "PHOTO"
+	if org {
+		image, name = "logo", "LOGO"
+	}
+	for _, value := range item.GetAll(image) {
+		s := value.Raw
+		if value.Kind == ItemValue {
+			if s = value.Item.GetString("contentUrl"); s == "" {
+				s = value.Item.GetString("url")
+			}
+		}
+		if s = strings.TrimSpace(s); s != "" {
+			c.add(name, s)
+		}
+	}
+
+	for _, s := range stringValues(item, "description") {
+		c.add("NOTE", escapeText(s))
+	}
+	for _, s := range stringValues(item, "url") {
+		c.add("URL", s)
+	}
+}
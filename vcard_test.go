@@ -0,0 +1,199 @@
+// Copyright 2015 Lars Wiegman. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package microdata
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func parseFirstItem(html string, t *testing.T) *Item {
+	data, err := ParseHTML(strings.NewReader(html), "text/html", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Items) == 0 {
+		t.Fatal("Result should have had an item")
+	}
+	return data.Items[0]
+}
+
+func TestToVCardHCard(t *testing.T) {
+	item := parseFirstItem(`
+		<div itemscope itemtype="http://microformats.org/profile/hcard">
+			<div itemprop="n" itemscope>
+				<span itemprop="given-name">Jack</span>
+				<span itemprop="family-name">Bauer</span>
+			</div>
+			<span itemprop="nickname">Jack; the agent, CTU</span>
+			<time itemprop="bday" datetime="1966-02-18">February 18</time>
+			<div itemprop="adr" itemscope>
+				<span itemprop="street-address">10201 W. Pico Blvd.</span>
+				<span itemprop="locality">Los Angeles</span>
+				<span itemprop="region">CA</span>
+				<span itemprop="postal-code">90064</span>
+				<meta itemprop="type" content="Work">
+			</div>
+			<div itemprop="tel" itemscope>
+				<span itemprop="value">+1 310 597 3781</span>
+				<meta itemprop="type" content="work">
+			</div>
+			<a itemprop="email" href="mailto:jack@example.com">Mail</a>
+			<div itemprop="org" itemscope>
+				<span itemprop="organization-name">Counter-Terrorist Unit</span>
+				<span itemprop="organization-unit">Field Operations</span>
+			</div>
+			<a itemprop="url" href="http://example.com/jack">Jack</a>
+		</div>`, t)
+
+	result, err := ToVCard(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Jack Bauer\r\n" +
+		"N:Bauer;Jack;;;\r\n" +
+		"BDAY:19660218\r\n" +
+		"ADR;TYPE=work:;;10201 W. Pico Blvd.;Los Angeles;CA;90064;\r\n" +
+		"TEL;TYPE=work:+1 310 597 3781\r\n" +
+		"EMAIL:jack@example.com\r\n" +
+		"ORG:Counter-Terrorist Unit;Field Operations\r\n" +
+		"NICKNAME:Jack\\; the agent\\, CTU\r\n" +
+		"URL:http://example.com/jack\r\n" +
+		"END:VCARD\r\n"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestToVCardPerson(t *testing.T) {
+	item := parseFirstItem(`
+		<div itemscope itemtype="https://schema.org/Person">
+			<span itemprop="name">Jane Doe</span>
+			<span itemprop="givenName">Jane</span>
+			<span itemprop="familyName">Doe</span>
+			<span itemprop="jobTitle">Professor</span>
+			<div itemprop="worksFor" itemscope itemtype="https://schema.org/Organization">
+				<span itemprop="name">University of Washington</span>
+			</div>
+			<div itemprop="address" itemscope itemtype="https://schema.org/PostalAddress">
+				<span itemprop="streetAddress">20341 Whitworth Institute
+405 N. Whitworth</span>
+				<span itemprop="addressLocality">Seattle</span>
+				<span itemprop="addressRegion">WA</span>
+				<span itemprop="postalCode">98052</span>
+			</div>
+			<span itemprop="telephone">(425) 123-4567</span>
+			<img itemprop="image" src="http://example.com/jane.jpg" alt="Jane">
+		</div>`, t)
+
+	result, err := ToVCard(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:Jane Doe\r\n" +
+		"N:Doe;Jane;;;\r\n" +
+		"ADR:;;20341 Whitworth Institute\\n405 N. Whitworth;Seattle;WA;98052;\r\n" +
+		"TEL:(425) 123-4567\r\n" +
+		"TITLE:Professor\r\n" +
+		"ORG:University of Washington\r\n" +
+		"PHOTO:http://example.com/jane.jpg\r\n" +
+		"END:VCARD\r\n"
+	if result != expected {
+		t.Errorf("Result should have been \"%s\", but it was \"%s\"", expected, result)
+	}
+}
+
+func TestToVCardUnsupportedType(t *testing.T) {
+	item := NewItem()
+	item.addType("http://schema.org/Product")
+
+	if _, err := ToVCard(item); err == nil {
+		t.Error("Result should have been an error for a Product item")
+	}
+	if _, err := ToVCard(nil); err == nil {
+		t.Error("Result should have been an error for a nil item")
+	}
+}
+
+func TestContentLinesFold(t *testing.T) {
+	c := &contentLines{}
+	c.add("NOTE", strings.Repeat("é", 80))
+
+	lines := strings.Split(strings.TrimSuffix(c.String(), "\r\n"), "\r\n")
+	if len(lines) != 3 {
+		t.Fatalf("Result should have had 3 lines, but it had %d", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) > 75 {
+			t.Errorf("Result should have had lines of at most 75 octets, but line %d had %d", i, len(line))
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("Result should have had continuation lines starting with a space, but it was \"%s\"", line)
+		}
+		if !utf8.ValidString(strings.TrimPrefix(line, " ")) {
+			t.Errorf("Result should not have split UTF-8 sequences, but line %d was \"%s\"", i, line)
+		}
+	}
+}
+
+// checkContentLines checks that the given vCard or iCalendar object has no
+// line breaks other than those ending content lines, and no content line
+// with one of the given names.
+func checkContentLines(result string, names []string, t *testing.T) {
+	for _, line := range strings.Split(strings.TrimSuffix(result, "\r\n"), "\r\n") {
+		if strings.ContainsAny(line, "\r\n") {
+			t.Errorf("Result should not have had line breaks within content lines, but it had \"%q\"", line)
+		}
+		for _, name := range names {
+			if strings.HasPrefix(line, name+":") {
+				t.Errorf("Result should not have had an injected \"%s\" line, but it had \"%s\"", name, line)
+			}
+		}
+	}
+}
+
+func TestToVCardHostileNewlines(t *testing.T) {
+	item := parseFirstItem(`
+		<div itemscope itemtype="https://schema.org/Person">
+			<span itemprop="name">Jane</span>
+			<meta itemprop="url" content="http://x/&#10;EMAIL:evil@example.com">
+			<meta itemprop="birthDate" content="2015&#13;&#10;NOTE:evil">
+			<meta itemprop="image" content="http://x/
+KEY:evil">
+		</div>`, t)
+
+	result, err := ToVCard(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContentLines(result, []string{"EMAIL", "NOTE", "KEY"}, t)
+
+	item = parseFirstItem(`
+		<div itemscope itemtype="http://microformats.org/profile/hcard">
+			<span itemprop="fn">Jack</span>
+			<div itemprop="tel" itemscope>
+				<meta itemprop="type" content="work&#10;EMAIL:evil@example.com">
+				<span itemprop="value">+1 310 597 3781</span>
+			</div>
+			<div itemprop="geo" itemscope>
+				<meta itemprop="latitude" content="1&#10;NOTE:evil">
+				<meta itemprop="longitude" content="2">
+			</div>
+			<meta itemprop="photo" content="http://x/&#10;KEY:evil">
+		</div>`, t)
+
+	result, err = ToVCard(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContentLines(result, []string{"EMAIL", "NOTE", "KEY"}, t)
+	if !strings.Contains(result, "TEL;TYPE=\"workemail:evil@example.com\":+1 310 597 3781\r\n") {
+		t.Errorf("Result should have had a quoted TYPE parameter, but it was \"%s\"", result)
+	}
+}